@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"github.com/spf13/cobra"
+
+	"github.com/kitos9112/terragrunt-gitlab-cicd-config/pkg/generator"
+)
+
+func lint(cmd *cobra.Command, args []string) error {
+	opts, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	gen, err := generator.New(opts)
+	if err != nil {
+		return err
+	}
+
+	findings, err := gen.Lint(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return reportFindings(findings, lintFormat, lintOutputPath)
+}
+
+var lintFormat string
+var lintOutputPath string
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Checks the modules of the repo against best-practice rules",
+	Long: `Checks the modules of the repo against the rules enabled in the config file.
+Exits with 2 when errors are found, and with 3 when only warnings are found`,
+	RunE: lint,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	addEvaluationFlags(lintCmd.PersistentFlags())
+	addFindingsFlags(lintCmd.PersistentFlags(), &lintFormat, &lintOutputPath)
+}
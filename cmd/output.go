@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeOutput calls `write` with stdout when `path` is `-`. Otherwise, the file at `path` is only replaced once `write`
+// succeeds, so a failing template never leaves a truncated configuration behind
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing the temp file fails once it has been renamed, which is fine
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	// The configuration keeps the mode of the file it replaces
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
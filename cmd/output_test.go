@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteOutput(t *testing.T) {
+	failure := errors.New("template failed")
+
+	tests := []struct {
+		name     string
+		existing *os.FileMode
+		write    func(w io.Writer) error
+		err      error
+		contents string
+		mode     os.FileMode
+	}{
+		{
+			name:     "new file",
+			write:    func(w io.Writer) error { _, err := io.WriteString(w, "new"); return err },
+			contents: "new",
+			mode:     0644,
+		},
+		{
+			name:     "replaced file keeps its mode",
+			existing: modePointer(0600),
+			write:    func(w io.Writer) error { _, err := io.WriteString(w, "new"); return err },
+			contents: "new",
+			mode:     0600,
+		},
+		{
+			name:     "failure keeps the file",
+			existing: modePointer(0640),
+			write: func(w io.Writer) error {
+				io.WriteString(w, "partial")
+				return failure
+			},
+			err:      failure,
+			contents: "old",
+			mode:     0640,
+		},
+		{
+			name: "failure writes nothing",
+			write: func(w io.Writer) error {
+				io.WriteString(w, "partial")
+				return failure
+			},
+			err: failure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, ".gitlab-ci.yml")
+			if tt.existing != nil {
+				if err := os.WriteFile(path, []byte("old"), *tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				// Not subject to the umask
+				if err := os.Chmod(path, *tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := writeOutput(path, tt.write); !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing == nil && tt.err != nil {
+				if len(entries) != 0 {
+					t.Errorf("got files %v, want none", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Errorf("got files %v, want only the output, without temp file", entries)
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(contents) != tt.contents {
+				t.Errorf("got contents %q, want %q", contents, tt.contents)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != tt.mode {
+				t.Errorf("got mode %v, want %v", info.Mode().Perm(), tt.mode)
+			}
+		})
+	}
+}
+
+func modePointer(mode os.FileMode) *os.FileMode {
+	return &mode
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitos9112/terragrunt-gitlab-cicd-config/pkg/generator"
+)
+
+// writeDataFile writes `data` as JSON to the file at `path`, or to stdout when `path` is `-`
+func writeDataFile(path string, data *generator.Data) error {
+	return writeOutput(path, func(w io.Writer) error {
+		return generator.WriteData(w, data)
+	})
+}
+
+// readDataFile reads the JSON written by `generate --dump-data` from the file at `path`
+func readDataFile(path string) (*generator.Data, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return generator.ReadData(file)
+}
+
+func render(cmd *cobra.Command, args []string) error {
+	data, err := readDataFile(renderDataPath)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(renderOutputPath, func(w io.Writer) error {
+		return generator.RenderData(w, renderInputTemplate, data)
+	})
+}
+
+var renderDataPath string
+var renderInputTemplate string
+var renderOutputPath string
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Renders GitLabCI Dynamic configuration from dumped data",
+	Long:  `Renders GitLabCI Dynamic configuration from the data written by generate --dump-data, without parsing the repo again`,
+	RunE:  render,
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.PersistentFlags().StringVar(&renderDataPath, "data", "", "Path of the JSON file written by generate --dump-data")
+	renderCmd.PersistentFlags().StringVar(&renderInputTemplate, "input", "", "Path of the file where Go Template configuration will be inputted")
+	renderCmd.PersistentFlags().StringVar(&renderOutputPath, "output", ".gitlab-ci.yml", "Path of the file where configuration will be generated, or \"-\" for stdout. Default is .gitlab-ci.yml")
+	renderCmd.MarkPersistentFlagRequired("data")
+	renderCmd.MarkPersistentFlagRequired("input")
+}
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/kitos9112/terragrunt-gitlab-cicd-config/pkg/generator"
 )
 
 var (
@@ -16,6 +22,62 @@ var rootCmd = &cobra.Command{
 	Use:   "terragrunt-gitlab-cicd-config",
 	Short: "Generates GitlabCI Config for Terragrunt projects",
 	Long:  "Generates GitlabCI Config for Terragrunt projects",
+	// Errors are printed by Execute
+	SilenceErrors: true,
+}
+
+// exitError makes the process exit with `code`. A nil `err` exits without printing anything
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+// Exit codes of `validate` and `lint`. Any other failure exits with 1
+const (
+	exitCodeValidationErrors   = 2
+	exitCodeValidationWarnings = 3
+)
+
+// addFindingsFlags adds the flags configuring how findings are reported, shared by every command reporting them
+func addFindingsFlags(flags *pflag.FlagSet, format *string, outputPath *string) {
+	flags.StringVar(format, "format", generator.FindingsFormatText, "Format of the findings; text, json, or codequality for a GitLab Code Quality report. Default is text")
+	flags.StringVar(outputPath, "output", "-", "Path of the file where findings are written, or \"-\" for stdout. Default is stdout")
+}
+
+// reportFindings writes `findings` in `format` to `outputPath`, or to stdout for "-", and returns the exitError
+// matching the worst of them
+func reportFindings(findings []generator.Finding, format string, outputPath string) error {
+	err := writeOutput(outputPath, func(w io.Writer) error {
+		return generator.WriteFindings(w, findings, format)
+	})
+	if err != nil {
+		return err
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, finding := range findings {
+		if finding.Severity == generator.SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	log.Info("Found ", errorCount, " errors and ", warningCount, " warnings")
+
+	if errorCount > 0 {
+		return &exitError{code: exitCodeValidationErrors}
+	}
+	if warningCount > 0 {
+		return &exitError{code: exitCodeValidationWarnings}
+	}
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -24,7 +86,14 @@ func Execute(version string) {
 	VERSION = version
 
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			if exitErr.err != nil {
+				fmt.Fprintln(os.Stderr, exitErr.err)
+			}
+			os.Exit(exitErr.code)
+		}
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
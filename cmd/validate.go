@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitos9112/terragrunt-gitlab-cicd-config/pkg/generator"
+)
+
+func validate(cmd *cobra.Command, args []string) error {
+	opts, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	gen, err := generator.New(opts)
+	if err != nil {
+		return err
+	}
+
+	findings, err := gen.Validate(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return reportFindings(findings, validateFormat, validateOutputPath)
+}
+
+var validateFormat string
+var validateOutputPath string
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks the Terragrunt layout of the repo",
+	Long: `Checks every Terragrunt config of the repo, reporting all problems found with their file and line.
+Exits with 2 when errors are found, and with 3 when only warnings are found`,
+	RunE: validate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	addEvaluationFlags(validateCmd.PersistentFlags())
+	addFindingsFlags(validateCmd.PersistentFlags(), &validateFormat, &validateOutputPath)
+}
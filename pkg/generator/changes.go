@@ -0,0 +1,303 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// globMetaCharacters are the characters that make a path segment a pattern
+const globMetaCharacters = `*?[\`
+
+// globMatches tells whether the absolute `pattern` matches any file or directory. Unlike `filepath.Glob`, `**` matches
+// any number of directories, like in GitLab `rules:changes`
+func globMatches(pattern string) (bool, error) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	static := 0
+	for static < len(segments) && !strings.ContainsAny(segments[static], globMetaCharacters) {
+		static++
+	}
+
+	prefix := filepath.FromSlash(strings.Join(segments[:static], "/"))
+	if static == len(segments) {
+		return util.FileExists(prefix), nil
+	}
+	if prefix == "" {
+		prefix = string(filepath.Separator)
+	}
+
+	matcher, err := regexp.Compile("^" + ignorePatternRegexp(strings.Join(segments[static:], "/")) + "$")
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	matched := false
+	err = filepath.WalkDir(prefix, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		relativePath, err := filepath.Rel(prefix, path)
+		if err != nil || relativePath == "." {
+			return err
+		}
+		if matcher.MatchString(filepath.ToSlash(relativePath)) {
+			matched = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return matched, err
+}
+
+// extraDependencies resolves the `gitlab_ci_extra_dependencies` of the config at `configPath` to absolute paths.
+// Directories become globs matching everything within them, and every entry must match at least one file
+func (g *Generator) extraDependencies(configPath string, entries []string) ([]string, error) {
+	dependencies := []string{}
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		absolutePath := entry
+		if !filepath.IsAbs(absolutePath) {
+			absolutePath = g.makePathAbsolute(entry, configPath)
+		}
+		absolutePath = filepath.Clean(absolutePath)
+
+		if info, err := os.Stat(absolutePath); err == nil && info.IsDir() {
+			dependencies = append(dependencies, filepath.Join(absolutePath, "**", "*"))
+			continue
+		}
+
+		matched, err := globMatches(absolutePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: gitlab_ci_extra_dependencies: %w", configPath, err)
+		}
+		if !matched {
+			return nil, fmt.Errorf("%s: gitlab_ci_extra_dependencies: %s does not match any file", configPath, entry)
+		}
+		dependencies = append(dependencies, absolutePath)
+	}
+	return dependencies, nil
+}
+
+// ignoredDependencies are the patterns of the dependencies a module ignores, by what they are ignored for
+type ignoredDependencies struct {
+	changes []*regexp.Regexp
+	cascade []*regexp.Regexp
+}
+
+// ignoredDependencies returns the dependencies the config at `configPath` ignores, from its
+// `gitlab_ci_ignore_dependencies` local and from the config file
+func (g *Generator) ignoredDependencies(configPath string, local IgnoreDependencies) (ignoredDependencies, error) {
+	changes, err := g.dependencyPatterns(configPath, g.opts.Config.Dependencies.Ignore.Changes, local.Changes)
+	if err != nil {
+		return ignoredDependencies{}, err
+	}
+	cascade, err := g.dependencyPatterns(configPath, g.opts.Config.Dependencies.Ignore.Cascade, local.Cascade)
+	if err != nil {
+		return ignoredDependencies{}, err
+	}
+	return ignoredDependencies{changes: changes, cascade: cascade}, nil
+}
+
+// dependencyPatterns compiles the config paths, directories or globs of ignored dependencies. Entries of the config
+// file are relative to the root, and the ones of the local to the config at `configPath`
+func (g *Generator) dependencyPatterns(configPath string, configEntries []string, localEntries []string) ([]*regexp.Regexp, error) {
+	patterns := []*regexp.Regexp{}
+	compile := func(entry string, baseDir string) error {
+		if entry == "" {
+			return nil
+		}
+		absolutePath := entry
+		if !filepath.IsAbs(absolutePath) {
+			absolutePath = filepath.Join(baseDir, entry)
+		}
+		pattern, err := regexp.Compile("^" + ignorePatternRegexp(filepath.ToSlash(filepath.Clean(absolutePath))) + "$")
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", entry, err)
+		}
+		patterns = append(patterns, pattern)
+		return nil
+	}
+
+	for _, entry := range configEntries {
+		if err := compile(entry, g.root); err != nil {
+			return nil, fmt.Errorf("config file: dependencies.ignore: %w", err)
+		}
+	}
+	for _, entry := range localEntries {
+		if err := compile(entry, filepath.Dir(configPath)); err != nil {
+			return nil, fmt.Errorf("%s: gitlab_ci_ignore_dependencies: %w", configPath, err)
+		}
+	}
+	return patterns, nil
+}
+
+// matchesDependency tells whether one of `patterns` matches the config at `dependencyPath`, or its directory
+func matchesDependency(patterns []*regexp.Regexp, dependencyPath string) bool {
+	dependencyPath = filepath.ToSlash(dependencyPath)
+	for _, pattern := range patterns {
+		if pattern.MatchString(dependencyPath) || pattern.MatchString(path.Dir(dependencyPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockFileName is the dependency lock file Terraform writes next to the code it runs
+const lockFileName = ".terraform.lock.hcl"
+
+// versionFileNames returns the names of the version files to look up
+func (g *Generator) versionFileNames() []string {
+	if g.opts.Config.Changes.VersionFiles == nil {
+		return DefaultVersionFiles
+	}
+	return g.opts.Config.Changes.VersionFiles
+}
+
+// versionFiles returns the closest version file of each name, looking up from `dir` to the root
+func (g *Generator) versionFiles(dir string) []string {
+	files := []string{}
+	for _, name := range g.versionFileNames() {
+		if file, ok := g.closestFile(dir, name); ok {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// lockFiles returns the lock files found in `dirs`
+func lockFiles(dirs ...string) []string {
+	files := []string{}
+	for _, dir := range dirs {
+		if file := filepath.Join(dir, lockFileName); util.FileExists(file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// ignoreChangesRules parses the patterns of files whose changes do not trigger the jobs of the module in `relativeDir`,
+// relative to the root. `origin` tells where the patterns come from, for logging
+func ignoreChangesRules(patterns []string, relativeDir string, origin string) (ignoreRules, error) {
+	if relativeDir == "." {
+		relativeDir = ""
+	}
+
+	rules := ignoreRules{}
+	for _, pattern := range patterns {
+		rule, ok, err := parseIgnoreLine(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", origin, err)
+		}
+		if ok {
+			rule.base = relativeDir
+			rule.file = origin
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// changeExtensions are the extensions of the files Terraform and Terragrunt read. In a directory with ignored files,
+// they are matched even when no such file is left, so that adding or removing one triggers the jobs
+var changeExtensions = []string{".hcl", ".json", ".tf", ".tfvars"}
+
+// moduleChanges returns the `rules:changes` globs of the files within the module directory `dir` that trigger its jobs,
+// relative to the root. Without any ignored file, that is the single `<dir>/**/*` glob. Otherwise, directories
+// without ignored files are matched as a whole, and the files of the others by extension, so that files added or
+// removed after the config was generated still trigger the jobs. Only the files sharing their extension with an
+// ignored file are matched one by one, so new files with such an extension, or with an extension no file of their
+// directory had, do not trigger the jobs until the config is generated again
+func (g *Generator) moduleChanges(dir string, rules ignoreRules) ([]string, error) {
+	changes, _, err := g.expandChanges(dir, rules)
+	return changes, err
+}
+
+// expandChanges returns the globs of the files within `dir` that are not ignored by `rules`, and whether none is
+func (g *Generator) expandChanges(dir string, rules ignoreRules) ([]string, bool, error) {
+	relativeDir := strings.TrimSuffix(g.relativePath(dir+string(filepath.Separator)), "/")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	complete := true
+	files := []string{}
+	ignoredExtensions := map[string]bool{}
+	dirChanges := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && slices.Contains(alwaysIgnoredDirs, entry.Name()) {
+			continue
+		}
+
+		relativeEntry := path.Join(relativeDir, entry.Name())
+		if rules.ignored(relativeEntry, entry.IsDir()) {
+			complete = false
+			if !entry.IsDir() {
+				ignoredExtensions[fileExtension(entry.Name())] = true
+			}
+			continue
+		}
+		if !entry.IsDir() {
+			files = append(files, entry.Name())
+			continue
+		}
+
+		entryChanges, entryComplete, err := g.expandChanges(filepath.Join(dir, entry.Name()), rules)
+		if err != nil {
+			return nil, false, err
+		}
+		complete = complete && entryComplete
+		dirChanges = append(dirChanges, entryChanges...)
+	}
+
+	if complete {
+		return []string{path.Join(relativeDir, "**", "*")}, true, nil
+	}
+	// Only subdirectories are ignored, so any file of the directory itself triggers the jobs
+	if len(ignoredExtensions) == 0 {
+		return append([]string{path.Join(relativeDir, "*")}, dirChanges...), false, nil
+	}
+
+	extensions := append([]string{}, changeExtensions...)
+	for _, file := range files {
+		extensions = append(extensions, fileExtension(file))
+	}
+	sort.Strings(extensions)
+
+	changes := []string{}
+	for _, extension := range uniqueStrings(extensions) {
+		if extension != "" && !ignoredExtensions[extension] {
+			changes = append(changes, path.Join(relativeDir, "*"+extension))
+		}
+	}
+	for _, file := range files {
+		if extension := fileExtension(file); extension == "" || ignoredExtensions[extension] {
+			changes = append(changes, path.Join(relativeDir, file))
+		}
+	}
+	return append(changes, dirChanges...), false, nil
+}
+
+// fileExtension returns the extension of the file `name`, or "" when it has none. Dot files, like `.envrc`, have none
+func fileExtension(name string) string {
+	extension := path.Ext(name)
+	if extension == name {
+		return ""
+	}
+	return extension
+}
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"context"
+	"path"
+	"strings"
+	"testing"
+)
+
+// changesMatch tells whether one of the `rules:changes` globs matches `file`, like GitLab does
+func changesMatch(changes []string, file string) bool {
+	for _, glob := range changes {
+		if prefix, ok := strings.CutSuffix(glob, "/**/*"); ok {
+			if strings.HasPrefix(file, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(glob, file); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func TestModuleChangesAfterGeneration(t *testing.T) {
+	g := newTestGenerator(t, "projects/ignore_changes", nil)
+	model, err := g.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []string
+	for _, module := range model.Modules {
+		if module.SourcePath == "app" {
+			changes = module.Dependencies
+		}
+	}
+
+	// None of these files exist: they are added or removed after the config was generated
+	tests := []struct {
+		file    string
+		matched bool
+	}{
+		{file: "app/main.tf", matched: true},
+		{file: "app/staging.tfvars", matched: true},
+		{file: "app/vars/staging.tfvars", matched: true},
+		{file: "app/vars/nested/common.yaml", matched: true},
+		// Known limits: the first has an extension no file of app had, the second shares its extension with the
+		// ignored README.md
+		{file: "app/config.yaml", matched: false},
+		{file: "app/CHANGELOG.md", matched: false},
+		{file: "app/docs/setup.md", matched: false},
+	}
+	for _, tt := range tests {
+		if matched := changesMatch(changes, tt.file); matched != tt.matched {
+			t.Errorf("changes %v matching %s: got %v, want %v", changes, tt.file, matched, tt.matched)
+		}
+	}
+}
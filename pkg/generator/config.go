@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigPath is where the config file is looked up when no path is given
+const DefaultConfigPath = ".tgci.yaml"
+
+// Config is the content of the config file, for settings that are shared by a whole repo
+type Config struct {
+	Lint         LintConfig         `yaml:"lint"`
+	Changes      ChangesConfig      `yaml:"changes"`
+	Dependencies DependenciesConfig `yaml:"dependencies"`
+	Matrix       []MatrixEntry      `yaml:"matrix"`
+	Metadata     MetadataConfig     `yaml:"metadata"`
+}
+
+// MetadataConfig configures the metadata files whose locals are exposed as the `Meta` of each module
+type MetadataConfig struct {
+	// Names of the metadata files, from the most general to the most specific, the closest of each being merged in that
+	// order. Unset means DefaultMetadataFiles, and an empty list none
+	Files []string `yaml:"files"`
+	// Metadata local holding the environment of a module, which `--environment` selects on. Modules without it are
+	// selected on their path instead. Default is `environment`
+	Environment string `yaml:"environment"`
+	// Metadata local grouping the dependencies of a module in `DependenciesGrouped`, looked up from the directory of
+	// each dependency. Dependencies without it, like every one when empty, are grouped by the first directory of their
+	// path
+	GroupBy string `yaml:"group_by"`
+}
+
+// DefaultMetadataFiles are the metadata files looked up when the config file does not list any
+var DefaultMetadataFiles = []string{"account.hcl", "region.hcl", "env.hcl"}
+
+// MatrixEntry evaluates the modules it matches once per environment variable set, unless they set the
+// `gitlab_ci_matrix` local themselves
+type MatrixEntry struct {
+	// Globs of the module directories, relative to the root, with `**` matching any number of directories
+	Modules []string `yaml:"modules"`
+	// Environment variable sets, each of which gives an entry per module
+	Env []map[string]string `yaml:"env"`
+}
+
+// ChangesConfig configures which changes trigger the jobs of the modules
+type ChangesConfig struct {
+	// Patterns, with the `.gitignore` syntax and relative to each module directory, of the files whose changes never
+	// trigger the jobs of the module. Added to the `gitlab_ci_ignore_changes` local of each module
+	Ignore []string `yaml:"ignore"`
+	// Names of the version files, like `.terraform-version`, the closest of which is a dependency of every module. Unset
+	// means DefaultVersionFiles, and an empty list none
+	VersionFiles []string `yaml:"version_files"`
+}
+
+// DependenciesConfig configures how the modules depend on each other
+type DependenciesConfig struct {
+	// Dependencies, relative to the root, that no module tracks. Added to the `gitlab_ci_ignore_dependencies` local of
+	// each module
+	Ignore IgnoreDependencies `yaml:"ignore"`
+}
+
+// IgnoreDependencies lists the config paths, directories or globs of the dependencies to ignore, apart for change
+// tracking and for cascading
+type IgnoreDependencies struct {
+	// Dependencies whose config changes do not trigger the jobs of the module. Their own dependencies are still
+	// cascaded
+	Changes []string `yaml:"changes"`
+	// Dependencies whose own dependencies are not cascaded. Their config changes still trigger the jobs of the module
+	Cascade []string `yaml:"cascade"`
+}
+
+// DefaultVersionFiles are the version files looked up when the config file does not list any
+var DefaultVersionFiles = []string{".terraform-version", ".terragrunt-version", ".tool-versions"}
+
+// LintConfig configures the `lint` rules
+type LintConfig struct {
+	// Rules by name. Only the rules listed here, and enabled, run
+	Rules map[string]LintRule `yaml:"rules"`
+}
+
+// LintRule configures a single lint rule. Settings that do not apply to the rule are ignored
+type LintRule struct {
+	Enabled bool `yaml:"enabled"`
+	// Severity of the findings of the rule. Default is warning
+	Severity Severity `yaml:"severity"`
+	// Regular expression refs must match to be considered a tag, for `git-ref-tag`
+	Pattern string `yaml:"pattern"`
+	// Environments modules are allowed to have, for `environment`. Empty means any
+	Allowed []string `yaml:"allowed"`
+	// Maximum number of dependency levels below a module, for `max-dependency-depth`
+	Max int `yaml:"max"`
+}
+
+// LoadConfig reads the config file at `path`. When `optional` is true, a missing file is the same as an empty one
+func LoadConfig(path string, optional bool) (Config, error) {
+	contents, err := os.ReadFile(path)
+	if optional && errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+	return cfg, nil
+}
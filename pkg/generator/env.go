@@ -0,0 +1,123 @@
+package generator
+
+// Configs are evaluated with a controlled set of environment variables rather than the runner's whole environment,
+// which usually holds CI secrets. The same environment is used for every config evaluated in a run, so `get_env()`
+// always evaluates the same way.
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/cli"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Parse env vars into a map
+func getEnvs() map[string]string {
+	envs := os.Environ()
+	m := make(map[string]string)
+
+	for _, env := range envs {
+		results := strings.SplitN(env, "=", 2)
+		if len(results) == 2 {
+			m[results[0]] = results[1]
+		}
+	}
+
+	return m
+}
+
+// buildEvaluationEnv builds the evaluation environment. Later sources take precedence over earlier ones:
+//   - variables of the current environment whose name matches one of the `passEnv` patterns
+//   - variables read from each of the `envFiles`, in order
+//   - the `setEnv` values
+func buildEvaluationEnv(passEnv []string, envFiles []string, setEnv map[string]string) (map[string]string, error) {
+	env := map[string]string{}
+
+	for name, value := range getEnvs() {
+		for _, pattern := range passEnv {
+			matched, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --pass-env pattern %q: %w", pattern, err)
+			}
+			if matched {
+				env[name] = value
+				break
+			}
+		}
+	}
+
+	for _, envFile := range envFiles {
+		fileEnv, err := parseEnvFile(envFile)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range fileEnv {
+			env[name] = value
+		}
+	}
+
+	for name, value := range setEnv {
+		env[name] = value
+	}
+
+	return env, nil
+}
+
+// parseEnvFile reads a dotenv style file: one `NAME=value` per line, with optional `export` prefixes, quoted values
+// and `#` comments
+func parseEnvFile(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", filename, lineNumber)
+		}
+
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value: %w", filename, lineNumber, err)
+			}
+		} else if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+			value = value[1 : len(value)-1]
+		}
+		env[name] = value
+	}
+
+	return env, scanner.Err()
+}
+
+// newTerragruntOptions creates the options to evaluate the config at `path` with, using the evaluation environment
+func (g *Generator) newTerragruntOptions(path string) (*options.TerragruntOptions, error) {
+	terragruntOptions, err := options.NewTerragruntOptions(path)
+	if err != nil {
+		return nil, err
+	}
+	terragruntOptions.RunTerragrunt = cli.RunTerragrunt
+	terragruntOptions.Env = util.CloneStringMap(g.env)
+	// The output of `run_cmd` must never end up in a configuration streamed to stdout
+	terragruntOptions.Writer = terragruntOptions.ErrWriter
+
+	return terragruntOptions, nil
+}
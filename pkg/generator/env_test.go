@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeEnvFile writes `contents` to a file of a temporary directory, and returns its path
+func writeEnvFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     map[string]string
+		valid    bool
+	}{
+		{name: "plain", contents: "NAME=value\nOTHER = spaced \n", want: map[string]string{"NAME": "value", "OTHER": "spaced"}, valid: true},
+		{name: "empty value", contents: "NAME=\n", want: map[string]string{"NAME": ""}, valid: true},
+		{name: "value with equals", contents: "URL=https://example.com/?a=b\n", want: map[string]string{"URL": "https://example.com/?a=b"}, valid: true},
+		{name: "export", contents: "export NAME=value\n  export OTHER=value\n", want: map[string]string{"NAME": "value", "OTHER": "value"}, valid: true},
+		{name: "double quotes", contents: `NAME="two words\nline" `, want: map[string]string{"NAME": "two words\nline"}, valid: true},
+		{name: "single quotes", contents: `NAME='raw \n # value'`, want: map[string]string{"NAME": `raw \n # value`}, valid: true},
+		{name: "lone single quote", contents: `NAME='`, want: map[string]string{"NAME": "'"}, valid: true},
+		{name: "comments and blank lines", contents: "# comment\n\n   # indented comment\nNAME=value\n", want: map[string]string{"NAME": "value"}, valid: true},
+		{name: "last one wins", contents: "NAME=first\nNAME=second\n", want: map[string]string{"NAME": "second"}, valid: true},
+		{name: "no equals", contents: "NAME\n"},
+		{name: "no name", contents: "=value\n"},
+		{name: "export alone", contents: "export NAME\n"},
+		{name: "unterminated double quotes", contents: `NAME="value`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnvFile(writeEnvFile(t, tt.contents))
+			if (err == nil) != tt.valid {
+				t.Fatalf("got error %v, want valid %v", err, tt.valid)
+			}
+			if tt.valid && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseEnvFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
+
+func TestBuildEvaluationEnv(t *testing.T) {
+	t.Setenv("TGCI_TEST_PASSED", "environment")
+	t.Setenv("TGCI_TEST_FILE", "environment")
+	t.Setenv("TGCI_TEST_SET", "environment")
+	t.Setenv("TGCI_OTHER", "environment")
+
+	envFile := writeEnvFile(t, "TGCI_TEST_FILE=file\nTGCI_TEST_SET=file\nTGCI_FILE_ONLY=file\n")
+	laterEnvFile := writeEnvFile(t, "TGCI_FILE_ONLY=later file\n")
+
+	tests := []struct {
+		name     string
+		passEnv  []string
+		envFiles []string
+		setEnv   map[string]string
+		want     map[string]string
+		valid    bool
+	}{
+		{
+			name:    "exact name",
+			passEnv: []string{"TGCI_OTHER"},
+			want:    map[string]string{"TGCI_OTHER": "environment"},
+			valid:   true,
+		},
+		{
+			name:    "glob",
+			passEnv: []string{"TGCI_TEST_*"},
+			want:    map[string]string{"TGCI_TEST_PASSED": "environment", "TGCI_TEST_FILE": "environment", "TGCI_TEST_SET": "environment"},
+			valid:   true,
+		},
+		{
+			name:    "single character glob",
+			passEnv: []string{"TGCI_TEST_SE?"},
+			want:    map[string]string{"TGCI_TEST_SET": "environment"},
+			valid:   true,
+		},
+		{
+			name:    "nothing passed",
+			passEnv: []string{},
+			want:    map[string]string{},
+			valid:   true,
+		},
+		{
+			name:     "set env over env files over passed env",
+			passEnv:  []string{"TGCI_TEST_*"},
+			envFiles: []string{envFile, laterEnvFile},
+			setEnv:   map[string]string{"TGCI_TEST_SET": "set"},
+			want: map[string]string{
+				"TGCI_TEST_PASSED": "environment",
+				"TGCI_TEST_FILE":   "file",
+				"TGCI_TEST_SET":    "set",
+				"TGCI_FILE_ONLY":   "later file",
+			},
+			valid: true,
+		},
+		{
+			name:    "invalid pattern",
+			passEnv: []string{"TGCI_["},
+		},
+		{
+			name:     "missing env file",
+			envFiles: []string{filepath.Join(t.TempDir(), "missing.env")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildEvaluationEnv(tt.passEnv, tt.envFiles, tt.setEnv)
+			if (err == nil) != tt.valid {
+				t.Fatalf("got error %v, want valid %v", err, tt.valid)
+			}
+			if !tt.valid {
+				return
+			}
+			// Only look at the variables of the test, the rest of the environment depends on the runner
+			for name := range got {
+				if !strings.HasPrefix(name, "TGCI_") {
+					delete(got, name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
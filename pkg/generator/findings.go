@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Severity tells whether a Finding should block the pipeline
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem found in the repo
+type Finding struct {
+	// Name of the check that found the problem
+	Check    string
+	Severity Severity
+	// Path of the file the problem is in, relative to the root
+	File string
+	// Line the problem is at. 0 when unknown
+	Line    int
+	Message string
+}
+
+func (f Finding) String() string {
+	location := f.File
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", location, f.Severity, f.Message, f.Check)
+}
+
+// sortFindings orders findings by file, then line, then message
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Message < findings[j].Message
+	})
+}
+
+// Formats findings can be written in
+const (
+	FindingsFormatText = "text"
+	FindingsFormatJSON = "json"
+	// https://docs.gitlab.com/ee/ci/testing/code_quality.html#implement-a-custom-tool
+	FindingsFormatCodeQuality = "codequality"
+)
+
+// codeQualityIssue is a finding in the GitLab Code Quality report format
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// codeQualitySeverities maps severities to the ones GitLab Code Quality knows about
+var codeQualitySeverities = map[Severity]string{
+	SeverityError:   "major",
+	SeverityWarning: "minor",
+}
+
+// WriteFindings writes `findings` to `w` in `format`
+func WriteFindings(w io.Writer, findings []Finding, format string) error {
+	switch format {
+	case FindingsFormatText:
+		for _, finding := range findings {
+			if _, err := fmt.Fprintln(w, finding); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FindingsFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(findings)
+	case FindingsFormatCodeQuality:
+		issues := []codeQualityIssue{}
+		for _, finding := range findings {
+			// The line is left out of the fingerprint, so a finding keeps its identity when lines are added above it
+			fingerprint := sha256.Sum256([]byte(finding.Check + "\x00" + finding.File + "\x00" + finding.Message))
+			line := finding.Line
+			if line < 1 {
+				line = 1
+			}
+			issues = append(issues, codeQualityIssue{
+				Description: finding.Message,
+				CheckName:   finding.Check,
+				Fingerprint: hex.EncodeToString(fingerprint[:]),
+				Severity:    codeQualitySeverities[finding.Severity],
+				Location:    codeQualityLocation{Path: finding.File, Lines: codeQualityLines{Begin: line}},
+			})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(issues)
+	default:
+		return fmt.Errorf("unknown findings format %q, expected one of %s, %s or %s", format, FindingsFormatText, FindingsFormatJSON, FindingsFormatCodeQuality)
+	}
+}
@@ -0,0 +1,1111 @@
+// Package generator collects the modules of a Terragrunt repo, along with the files each of them depends on, and
+// renders them through a Go template into a GitLab CI configuration.
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
+
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Options configures a Generator
+type Options struct {
+	// Path to the root directory of the git repo to build config for
+	Root string
+	// Name of the environment folder within `Root` to generate config for. Empty means every environment
+	Environment string
+	// When true, the environment name is not shortened to its GitLab deployment tier
+	PreserveEnvironment bool
+	// Values the metadata of a module must have for the module to be kept, by `meta.` prefixed dotted path, like
+	// `meta.environment`. Empty means every module
+	Select map[string]string
+	// When true, dependencies found in `dependency` blocks are ignored
+	IgnoreDependencyBlocks bool
+	// When true, plans and applies can happen in parallel
+	Parallel bool
+	// When true, a module depends not only on its dependencies, but on all dependencies of its dependencies
+	CascadeDependencies bool
+	// Maximum number of modules parsed concurrently
+	Parallelism int64
+	// Path of the Go template to render
+	InputTemplate string
+	// When true, `.gitignore` files are read along with `.tgciignore` files to skip directories and configs
+	RespectGitignore bool
+	// Local directories remote `terraform.source` prefixes are mapped to, by prefix. Mapped sources are tracked like
+	// local ones
+	SourceMap map[string]string
+	// Settings of the config file
+	Config Config
+	// What to do when several modules store their state at the same location; DuplicateStateKeysWarn or
+	// DuplicateStateKeysError. Default is to warn
+	DuplicateStateKeys string
+
+	// Names of the environment variables passed through to config evaluation. Supports `*` wildcards, `*` passing the
+	// whole environment. Default is DefaultPassEnv
+	PassEnv []string
+	// Paths of dotenv files with environment variables to evaluate configs with
+	EnvFiles []string
+	// Environment variables to evaluate configs with
+	SetEnv map[string]string
+
+	// When true, Terragrunt functions with side effects return deterministic stubs
+	Sandbox bool
+	// Values returned by sandboxed functions, by `function` or `function:args`
+	SandboxValues map[string]string
+}
+
+// DefaultOptions returns the Options the `generate` command uses when no flag is given
+func DefaultOptions() Options {
+	return Options{
+		Root:                ".",
+		Parallel:            true,
+		CascadeDependencies: true,
+		Parallelism:         500,
+		PassEnv:             DefaultPassEnv,
+	}
+}
+
+// DefaultPassEnv are the variables passed through to config evaluation by default, which leaves out CI secrets
+var DefaultPassEnv = []string{"HOME", "PATH"}
+
+type DependencyDirs struct {
+	// Module folder Where Terragrunt should run
+	SourcePath string
+	// Identity of the entry, unique among all of them, for example to name its jobs. The source path, followed by
+	// `[NAME=value,...]` for the entries of a module with a matrix, and by `@<workspace>` for the ones of a module with
+	// workspaces
+	ID string
+	// Environment variables of the matrix set the entry was evaluated under, from the `gitlab_ci_matrix` local or the
+	// config file. A module with a matrix has one entry per set. Empty otherwise
+	Matrix map[string]string
+	// Terraform workspace the jobs of the entry run in, from the `gitlab_ci_workspaces` local. A module with workspaces
+	// has one entry per workspace. Empty otherwise
+	Workspace string
+	// Extra environment variables of the workspace. Empty when there is none
+	WorkspaceEnv map[string]string
+	// List of releative path dependencies
+	Dependencies []string
+	// Dependencies grouped by directory (environment)
+	DependenciesGrouped []EnvironmentGroup
+	// Environment set by the `gitlab_ci_environment` local. Empty when not set
+	Environment string
+	// Locals of the closest metadata files, like `env.hcl`, merged from the most general to the most specific
+	Meta map[string]interface{}
+	// Key of the state within the backend of the `remote_state` block, like the S3 key. Empty when not set
+	StateKey string
+	// Parsed `terraform.source`. Nil when not set
+	Source *Source
+	// What the Terraform code the module runs declares, when it is a local source or, without source, the module
+	// directory itself. Nil otherwise
+	Terraform *TerraformModule
+	// Files of the repo the hooks and `generate` blocks of the module, or of the configs it includes, refer to, sorted
+	// by path. They are part of Dependencies too
+	FileDependencies []FileDependency
+
+	// Workspaces the module fans out into
+	workspaces []Workspace
+}
+
+type EnvironmentGroup struct {
+	Environment string
+	Items       []string
+}
+
+// Graph holds the `dependency` and `dependencies` blocks between modules
+type Graph struct {
+	// Modules each module depends on. Both are paths relative to the root, like `DependencyDirs.SourcePath`, but for
+	// the entries of a module with a matrix, which are nodes of their own keyed by their `DependencyDirs.ID`, like
+	// `network[REGION=us-east-1]`. An entry depends on the entry of a module with a matrix under the same set, or on all
+	// its entries when there is none
+	Edges map[string][]string
+	// Edges inferred from the `terraform_remote_state` data sources of the Terraform code of each module, matched
+	// against the `remote_state` of the other modules. They are part of Edges too, but deserve a review, as they may
+	// only be a way around a missing `dependency` block. Unlike the edges of `dependency` blocks, they add nothing to
+	// the `Dependencies` of the module, so changes to the module depended on do not trigger its jobs
+	Inferred map[string][]string
+	// Edges of each module, along with what declares them, sorted by dependency
+	Details map[string][]Edge
+}
+
+// Kinds of edges
+const (
+	// Declared by a `dependency` block, whose outputs the module may read
+	EdgeKindDependency = "dependency"
+	// Declared by the `dependencies` block, which only orders the modules
+	EdgeKindDependencies = "dependencies"
+	// Inferred from a `terraform_remote_state` data source
+	EdgeKindInferred = "inferred"
+)
+
+// Edge is a dependency of a module on another
+type Edge struct {
+	// Module depended on, relative to the root
+	Dependency string
+	// What declares the edge, one of the EdgeKind constants. A module declared by both a `dependency` block and the
+	// `dependencies` block has a single edge, of the `dependency` kind
+	Kind string
+	// Name of the `dependency` block. Empty for the other kinds
+	Name string
+	// Whether the `dependency` block sets `mock_outputs`
+	MockOutputs bool
+	// The `mock_outputs_allowed_terraform_commands` of the `dependency` block. Nil when unset, in which case Terragrunt
+	// allows the mocks for every command
+	MockOutputsAllowedTerraformCommands []string
+	// Whether the `dependency` block sets `skip_outputs`
+	SkipOutputs bool
+}
+
+// MockedFor tells whether Terragrunt uses the mock outputs of the dependency when running `command`, like `plan`,
+// before the dependency is applied
+func (e Edge) MockedFor(command string) bool {
+	return e.MockOutputs && (e.MockOutputsAllowedTerraformCommands == nil || slices.Contains(e.MockOutputsAllowedTerraformCommands, command))
+}
+
+// WaitsFor tells whether running `command`, like `plan`, in the module needs the dependency to be applied first. That
+// is the case of the `dependency` blocks whose outputs are read and not mocked for that command
+func (e Edge) WaitsFor(command string) bool {
+	return e.Kind == EdgeKindDependency && !e.SkipOutputs && !e.MockedFor(command)
+}
+
+// Model is everything collected about the modules of a repo
+type Model struct {
+	// Modules to generate jobs for, sorted by their source path
+	Modules []DependencyDirs
+	// Dependency graph between the modules
+	Graph Graph
+}
+
+// Generator collects the modules of a repo and renders them. All state lives on the Generator, so several of them can
+// be used in the same process.
+type Generator struct {
+	opts Options
+
+	// Absolute path of the root, with a trailing separator
+	root string
+	// Environment visible to `get_env()` and `run_cmd()` while evaluating configs
+	env     map[string]string
+	sandbox *sandbox
+	store   *parsedFileStore
+
+	requestGroup         singleflight.Group
+	getDependenciesCache *GetDependenciesCache
+
+	edgesMtx sync.Mutex
+	// Config paths of the modules each config depends on, by absolute config path
+	edges map[string][]string
+	// Edges of the modules each config depends on, by absolute config path
+	edgeDetails map[string][]Edge
+
+	stateMtx sync.Mutex
+	// Source paths of the modules storing their state at each location
+	stateLocations map[string][]string
+	// Locations of the states read by the Terraform code of each module, by source path
+	stateReads map[string][]string
+
+	// Makes sure each directory of Terraform code is only loaded once, while other directories load concurrently
+	terraformGroup singleflight.Group
+
+	terraformMtx sync.RWMutex
+	// Terraform code loaded so far, by absolute directory
+	terraformModules map[string]*TerraformModule
+
+	moduleCallsMtx sync.RWMutex
+	// Module-call graph of the Terraform code loaded so far, by absolute directory
+	moduleCallNodes map[string]*moduleCallNode
+	// Trees of the local modules called from each directory, when they have no cycle, by absolute directory
+	moduleCallSubtrees map[string][]ModuleCall
+
+	// Configs included by the configs found under the root, by absolute path. Only written before modules are parsed
+	includedConfigs map[string]bool
+
+	fileDependenciesMtx sync.Mutex
+	// Files the hooks and `generate` blocks of each config refer to, by absolute config path
+	fileDependencies map[string][]FileDependency
+
+	metadataMtx sync.Mutex
+	// Locals of the metadata files evaluated so far, by absolute path
+	metadataFiles map[string]map[string]interface{}
+	// Merged metadata of each module directory looked up so far, by absolute directory
+	metadataDirs map[string]map[string]interface{}
+
+	closestFilesMtx sync.Mutex
+	// Closest file of each name looked up so far, by absolute directory joined with the name. Empty when there is none
+	closestFiles map[string]string
+
+	matrixMtx sync.Mutex
+	// Generators evaluating configs under each matrix set, by matrixKey
+	matrixGenerators map[string]*Generator
+	// Absolute config paths of the modules that got an entry under each matrix set, by matrixKey
+	matrixProjects map[string]map[string]bool
+
+	model *Model
+}
+
+// New validates the options and creates a Generator
+func New(opts Options) (*Generator, error) {
+	if opts.Parallelism < 1 {
+		return nil, fmt.Errorf("parallelism must be at least 1, got %d", opts.Parallelism)
+	}
+	if opts.DuplicateStateKeys != "" && opts.DuplicateStateKeys != DuplicateStateKeysWarn && opts.DuplicateStateKeys != DuplicateStateKeysError {
+		return nil, fmt.Errorf("duplicate state keys must be %s or %s, got %q", DuplicateStateKeysWarn, DuplicateStateKeysError, opts.DuplicateStateKeys)
+	}
+
+	// Ensure the root has a trailing slash and is an absolute path
+	absoluteRoot, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ignoreChangesRules(opts.Config.Changes.Ignore, "", "config file"); err != nil {
+		return nil, err
+	}
+	if err := validateSelect(opts.Select); err != nil {
+		return nil, err
+	}
+
+	env, err := buildEvaluationEnv(opts.PassEnv, opts.EnvFiles, opts.SetEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Generator{
+		opts:                 opts,
+		root:                 absoluteRoot + string(filepath.Separator),
+		env:                  env,
+		sandbox:              newSandbox(opts.Sandbox, opts.SandboxValues),
+		store:                newParsedFileStore(),
+		getDependenciesCache: newGetDependenciesCache(),
+		edges:                map[string][]string{},
+		edgeDetails:          map[string][]Edge{},
+		stateLocations:       map[string][]string{},
+		stateReads:           map[string][]string{},
+		fileDependencies:     map[string][]FileDependency{},
+		terraformModules:     map[string]*TerraformModule{},
+		moduleCallNodes:      map[string]*moduleCallNode{},
+		moduleCallSubtrees:   map[string][]ModuleCall{},
+		includedConfigs:      map[string]bool{},
+		metadataFiles:        map[string]map[string]interface{}{},
+		metadataDirs:         map[string]map[string]interface{}{},
+		closestFiles:         map[string]string{},
+		matrixGenerators:     map[string]*Generator{},
+		matrixProjects:       map[string]map[string]bool{},
+	}
+
+	// Only the patterns of the config file are checked here, those of the locals are checked per module
+	if _, err := g.ignoredDependencies("", IgnoreDependencies{}); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// SandboxCalls returns every distinct call made to a sandboxed function so far
+func (g *Generator) SandboxCalls() []SandboxCall {
+	return g.sandbox.Calls()
+}
+
+// WriteSandboxRecord writes every call made to a sandboxed function so far to `path` as JSON
+func (g *Generator) WriteSandboxRecord(path string) error {
+	return g.sandbox.writeRecord(path)
+}
+
+// Terragrunt imports can be relative or absolute
+// This makes relative paths absolute
+func (g *Generator) makePathAbsolute(path string, parentPath string) string {
+	if strings.HasPrefix(path, filepath.ToSlash(g.root)) {
+		return path
+	}
+
+	parentDir := filepath.Dir(parentPath)
+	return filepath.Join(parentDir, path)
+}
+
+// Set up a cache for the getDependencies function
+type getDependenciesOutput struct {
+	dependencies []string
+	err          error
+}
+
+type GetDependenciesCache struct {
+	mtx  sync.RWMutex
+	data map[string]getDependenciesOutput
+}
+
+func newGetDependenciesCache() *GetDependenciesCache {
+	return &GetDependenciesCache{data: map[string]getDependenciesOutput{}}
+}
+
+func (m *GetDependenciesCache) set(k string, v getDependenciesOutput) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.data[k] = v
+}
+
+func (m *GetDependenciesCache) get(k string) (getDependenciesOutput, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	v, ok := m.data[k]
+	return v, ok
+}
+
+func uniqueStrings(str []string) []string {
+	keys := make(map[string]bool)
+	list := []string{}
+	for _, entry := range str {
+		if _, value := keys[entry]; !value {
+			keys[entry] = true
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+func lookupProjectHcl(m map[string][]string, value string) (key string) {
+	for k, values := range m {
+		for _, val := range values {
+			if val == value {
+				key = k
+				return
+			}
+		}
+	}
+	return key
+}
+
+// sliceUnion takes two slices of strings and produces a union of them, containing only unique values
+func sliceUnion(a, b []string) []string {
+	m := make(map[string]bool)
+
+	for _, item := range a {
+		m[item] = true
+	}
+
+	for _, item := range b {
+		if _, ok := m[item]; !ok {
+			a = append(a, item)
+		}
+	}
+	return a
+}
+
+// The blocks `getDependencies` decodes
+var dependenciesDecodeList = []config.PartialDecodeSectionType{
+	config.DependencyBlock,
+	config.DependenciesBlock,
+	config.TerraformBlock,
+}
+
+// Parses the terragrunt config at `path` to find all modules it depends on
+func (g *Generator) getDependencies(path string, terragruntOptions *options.TerragruntOptions) ([]string, error) {
+	res, err, _ := g.requestGroup.Do(path, func() (interface{}, error) {
+		// Check if this path has already been computed
+		cachedResult, ok := g.getDependenciesCache.get(path)
+		if ok {
+			return cachedResult.dependencies, cachedResult.err
+		}
+
+		// parse the module path to find what it includes, as well as whether it is a module at all
+		// return nils to indicate we should skip this project
+		role, includes, err := g.parseModule(path, terragruntOptions)
+		if err != nil {
+			g.getDependenciesCache.set(path, getDependenciesOutput{nil, err})
+			return nil, err
+		}
+		if role != roleModule {
+			g.getDependenciesCache.set(path, getDependenciesOutput{nil, nil})
+			return nil, nil
+		}
+
+		dependencies := []string{}
+		if len(includes) > 0 {
+			for _, includeDep := range includes {
+				includedPath := includePath(path, includeDep)
+				g.getDependenciesCache.set(includedPath, getDependenciesOutput{nil, err})
+				dependencies = append(dependencies, includedPath)
+
+				// Along with the configs it includes in turn
+				for _, nestedPath := range g.nestedIncludePaths(includedPath, terragruntOptions, []string{path}) {
+					g.getDependenciesCache.set(nestedPath, getDependenciesOutput{nil, nil})
+					dependencies = append(dependencies, nestedPath)
+				}
+			}
+		}
+
+		// Parse the HCL file
+		parsedConfig, err := g.partialParseConfigFile(path, terragruntOptions, nil, dependenciesDecodeList)
+		if err != nil {
+			g.getDependenciesCache.set(path, getDependenciesOutput{nil, err})
+			return nil, err
+		}
+
+		// Parse out locals
+		locals, err := g.parseLocals(path, terragruntOptions, nil)
+		if err != nil {
+			g.getDependenciesCache.set(path, getDependenciesOutput{nil, err})
+			return nil, err
+		}
+
+		// Get deps from locals
+		if locals.ExtraGitlabCiDependencies != nil {
+			dependencies = sliceUnion(dependencies, locals.ExtraGitlabCiDependencies)
+		}
+		if locals.ExtraDependencies != nil {
+			extraDependencies, err := g.extraDependencies(path, locals.ExtraDependencies)
+			if err != nil {
+				g.getDependenciesCache.set(path, getDependenciesOutput{nil, err})
+				return nil, err
+			}
+			dependencies = sliceUnion(dependencies, extraDependencies)
+		}
+
+		// Get deps from `dependencies` and `dependency` blocks, apart from the ones the module ignores. The ones only
+		// ignored for change tracking are still cascaded, and the ones only ignored for cascading still tracked
+		ignored, err := g.ignoredDependencies(path, locals.IgnoreDependencies)
+		if err != nil {
+			g.getDependenciesCache.set(path, getDependenciesOutput{nil, err})
+			return nil, err
+		}
+		cascadeOnlyDeps := []string{}
+		notCascadedDeps := map[string]bool{}
+		if parsedConfig.Dependencies != nil {
+			g.recordEdges(path, parsedConfig.Dependencies.Paths)
+			g.recordEdgeDetails(path, parsedConfig.TerragruntDependencies, parsedConfig.Dependencies.Paths)
+
+			if !g.opts.IgnoreDependencyBlocks {
+				for _, parsedPaths := range parsedConfig.Dependencies.Paths {
+					dependencyPath := filepath.Join(parsedPaths, "terragrunt.hcl")
+					absolutePath := filepath.ToSlash(g.makePathAbsolute(dependencyPath, path))
+					ignoreChanges := matchesDependency(ignored.changes, absolutePath)
+					ignoreCascade := matchesDependency(ignored.cascade, absolutePath)
+					if ignoreChanges || ignoreCascade {
+						log.Debug(path, " ignores ", absolutePath, " for changes: ", ignoreChanges, ", for cascading: ", ignoreCascade)
+					}
+
+					switch {
+					case ignoreChanges && ignoreCascade:
+						continue
+					case ignoreChanges:
+						cascadeOnlyDeps = append(cascadeOnlyDeps, absolutePath)
+					case ignoreCascade:
+						dependencies = append(dependencies, dependencyPath)
+						notCascadedDeps[absolutePath] = true
+					default:
+						dependencies = append(dependencies, dependencyPath)
+					}
+				}
+			}
+		}
+
+		// Get deps from the `Source` field of the `Terraform` block
+		if parsedConfig.Terraform != nil && parsedConfig.Terraform.Source != nil {
+			source, err := g.parseSource(*parsedConfig.Terraform.Source, path)
+			if err != nil {
+				return nil, err
+			}
+
+			// Local sources, including remote ones mapped to a local directory, are part of the repo
+			if source.Type == SourceTypeLocal {
+				localDir := filepath.Join(g.absoluteDir(source), source.Subdir)
+
+				dependencies = append(dependencies, filepath.Join(localDir, "*.tf*"))
+
+				ls, err := g.parseTerraformLocalModuleSource(localDir)
+				if err != nil {
+					return nil, err
+				}
+
+				dependencies = append(dependencies, ls...)
+			}
+		}
+
+		// Get deps from `extra_arguments` fields of the `Terraform` block
+		if parsedConfig.Terraform != nil && parsedConfig.Terraform.ExtraArgs != nil {
+			extraArgs := parsedConfig.Terraform.ExtraArgs
+			for _, arg := range extraArgs {
+				if arg.RequiredVarFiles != nil {
+					dependencies = append(dependencies, *arg.RequiredVarFiles...)
+				}
+				if arg.OptionalVarFiles != nil {
+					dependencies = append(dependencies, *arg.OptionalVarFiles...)
+				}
+				if arg.Arguments != nil {
+					for _, cliFlag := range *arg.Arguments {
+						if strings.HasPrefix(cliFlag, "-var-file=") {
+							dependencies = append(dependencies, strings.TrimPrefix(cliFlag, "-var-file="))
+						}
+					}
+				}
+			}
+		}
+
+		// Filter out and dependencies that are the empty string
+		nonEmptyDeps := []string{}
+		for _, dep := range dependencies {
+			if dep != "" {
+				childDepAbsPath := dep
+				if !filepath.IsAbs(childDepAbsPath) {
+					childDepAbsPath = g.makePathAbsolute(dep, path)
+				}
+				childDepAbsPath = filepath.ToSlash(childDepAbsPath)
+				nonEmptyDeps = append(nonEmptyDeps, childDepAbsPath)
+			}
+		}
+
+		// Recurse to find dependencies of all dependencies
+		cascadedDeps := []string{}
+		for i, dep := range append(nonEmptyDeps, cascadeOnlyDeps...) {
+			if i < len(nonEmptyDeps) {
+				cascadedDeps = append(cascadedDeps, dep)
+			}
+
+			// The "cascading" feature is protected by a flag
+			if !g.opts.CascadeDependencies || notCascadedDeps[dep] {
+				continue
+			}
+
+			depPath := dep
+			terrOpts, _ := g.newTerragruntOptions(depPath)
+			terrOpts.OriginalTerragruntConfigPath = terragruntOptions.OriginalTerragruntConfigPath
+			childDeps, err := g.getDependencies(depPath, terrOpts)
+			if err != nil {
+				continue
+			}
+
+			for _, childDep := range childDeps {
+				// If `childDep` is a relative path, it will be relative to `childDep`, as it is from the nested
+				// `getDependencies` call on the top level module's dependencies. So here we update any relative
+				// path to be from the top level module instead.
+				childDepAbsPath := childDep
+				if !filepath.IsAbs(childDep) {
+					childDepAbsPath, err = filepath.Abs(filepath.Join(depPath, "..", childDep))
+					if err != nil {
+						g.getDependenciesCache.set(path, getDependenciesOutput{nil, err})
+						return nil, err
+					}
+				}
+				childDepAbsPath = filepath.ToSlash(childDepAbsPath)
+
+				// Dependencies ignored for change tracking are left out wherever they come from
+				if matchesDependency(ignored.changes, childDepAbsPath) {
+					continue
+				}
+
+				// Ensure we are not adding a duplicate dependency
+				alreadyExists := false
+				for _, dep := range cascadedDeps {
+					if dep == childDepAbsPath {
+						alreadyExists = true
+						break
+					}
+				}
+				if !alreadyExists {
+					cascadedDeps = append(cascadedDeps, childDepAbsPath)
+				}
+			}
+		}
+
+		if filepath.Base(path) == "terragrunt.hcl" {
+			dir := filepath.Dir(path)
+
+			ls, err := g.parseTerraformLocalModuleSource(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			cascadedDeps = append(cascadedDeps, ls...)
+
+			// The lock files and the closest version files change what gets deployed. They are added after cascading,
+			// as they are not configs to parse
+			lockDirs := []string{dir}
+			if parsedConfig.Terraform != nil && parsedConfig.Terraform.Source != nil {
+				source, err := g.parseSource(*parsedConfig.Terraform.Source, path)
+				if err == nil && source.Type == SourceTypeLocal {
+					lockDirs = append(lockDirs, filepath.Join(g.absoluteDir(source), source.Subdir))
+				}
+			}
+			for _, file := range append(lockFiles(lockDirs...), g.versionFiles(dir)...) {
+				cascadedDeps = append(cascadedDeps, filepath.ToSlash(file))
+			}
+
+			// So are the scripts the hooks run and the templates of the `generate` blocks
+			fileDependencies, err := g.hookFiles(path, terragruntOptions, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			g.recordFileDependencies(path, fileDependencies)
+			for _, file := range fileDependencies {
+				cascadedDeps = append(cascadedDeps, filepath.ToSlash(filepath.Join(g.root, file.Path)))
+			}
+		}
+
+		g.getDependenciesCache.set(path, getDependenciesOutput{cascadedDeps, err})
+		return cascadedDeps, nil
+	})
+
+	if res != nil {
+		return res.([]string), err
+	} else {
+		return nil, err
+	}
+}
+
+// recordEdges keeps track of the modules the config at `path` depends on, to build the module graph from
+func (g *Generator) recordEdges(path string, dependencyPaths []string) {
+	configPaths := []string{}
+	for _, dependencyPath := range dependencyPaths {
+		if !filepath.IsAbs(dependencyPath) {
+			dependencyPath = filepath.Join(filepath.Dir(path), dependencyPath)
+		}
+		configPaths = append(configPaths, config.GetDefaultConfigPath(filepath.Clean(dependencyPath)))
+	}
+
+	g.edgesMtx.Lock()
+	defer g.edgesMtx.Unlock()
+	g.edges[path] = configPaths
+}
+
+// recordFileDependencies keeps track of the files the hooks and `generate` blocks of the config at `path` refer to
+func (g *Generator) recordFileDependencies(path string, files []FileDependency) {
+	g.fileDependenciesMtx.Lock()
+	defer g.fileDependenciesMtx.Unlock()
+	g.fileDependencies[path] = files
+}
+
+// recordEdgeDetails keeps track of what declares the edges of the config at `path`: its `dependency` blocks, and the
+// `dependencies` paths left
+func (g *Generator) recordEdgeDetails(path string, dependencyBlocks []config.Dependency, dependencyPaths []string) {
+	dependencyDir := func(dependencyPath string) string {
+		if !filepath.IsAbs(dependencyPath) {
+			dependencyPath = filepath.Join(filepath.Dir(path), dependencyPath)
+		}
+		return g.relativeModuleDir(config.GetDefaultConfigPath(filepath.Clean(dependencyPath)))
+	}
+
+	edges := []Edge{}
+	declared := map[string]bool{}
+	for _, block := range dependencyBlocks {
+		edge := Edge{
+			Dependency:  dependencyDir(block.ConfigPath),
+			Kind:        EdgeKindDependency,
+			Name:        block.Name,
+			MockOutputs: block.MockOutputs != nil && !block.MockOutputs.IsNull(),
+			SkipOutputs: block.SkipOutputs != nil && *block.SkipOutputs,
+		}
+		if block.MockOutputsAllowedTerraformCommands != nil {
+			edge.MockOutputsAllowedTerraformCommands = append([]string{}, *block.MockOutputsAllowedTerraformCommands...)
+		}
+		declared[edge.Dependency] = true
+		edges = append(edges, edge)
+	}
+	for _, dependencyPath := range dependencyPaths {
+		dir := dependencyDir(dependencyPath)
+		if declared[dir] {
+			continue
+		}
+		declared[dir] = true
+		edges = append(edges, Edge{Dependency: dir, Kind: EdgeKindDependencies})
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].Dependency < edges[j].Dependency
+	})
+
+	g.edgesMtx.Lock()
+	defer g.edgesMtx.Unlock()
+	g.edgeDetails[path] = edges
+}
+
+// relativeModuleDir returns the directory of the config at `configPath`, relative to the root
+func (g *Generator) relativeModuleDir(configPath string) string {
+	relativeDir := strings.TrimPrefix(filepath.Dir(configPath)+string(filepath.Separator), g.root)
+	relativeDir = strings.TrimSuffix(relativeDir, string(filepath.Separator))
+	if relativeDir == "" {
+		return "."
+	}
+	return filepath.ToSlash(relativeDir)
+}
+
+// Creates a Project for a directory
+func (g *Generator) createProject(sourcePath string) (*DependencyDirs, error) {
+	options, err := g.newTerragruntOptions(sourcePath)
+	log.Debug("Working at: ", sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	options.OriginalTerragruntConfigPath = sourcePath
+
+	dependencies, err := g.getDependencies(sourcePath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// dependencies being nil is a sign from `getDependencies` that this project should be skipped
+	if dependencies == nil {
+		return nil, nil
+	}
+
+	locals, err := g.parseLocals(sourcePath, options, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// If `gitlabci_skip` is true on the module, then do not produce a project for it
+	if locals.Skip != nil && *locals.Skip {
+		return nil, nil
+	}
+
+	// Clean up the relative path to the format Atlantis expects
+	relativeSourceDir := g.relativeModuleDir(sourcePath)
+
+	// Add local changes inside that directory where `terragrunt.hcl` lives
+	terragruntDep := fmt.Sprintf("%s%s", relativeSourceDir, "/**/*")
+
+	relativeDependencies := []string{
+		terragruntDep,
+	}
+
+	// Unless some of them are ignored, in which case the rest is listed
+	ignoreChanges, err := ignoreChangesRules(g.opts.Config.Changes.Ignore, relativeSourceDir, "config file")
+	if err != nil {
+		return nil, err
+	}
+	localIgnoreChanges, err := ignoreChangesRules(locals.IgnoreChanges, relativeSourceDir, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	ignoreChanges = append(ignoreChanges, localIgnoreChanges...)
+	if len(ignoreChanges) > 0 {
+		relativeDependencies, err = g.moduleChanges(filepath.Dir(sourcePath), ignoreChanges)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Add other dependencies based on their relative paths. We always want to output with Unix path separators
+	for _, dependencyPath := range dependencies {
+		absolutePath := dependencyPath
+		if !filepath.IsAbs(absolutePath) {
+			absolutePath = g.makePathAbsolute(dependencyPath, sourcePath)
+		}
+		log.Debug("Dealing with dependencyPath ", dependencyPath)
+		relativeDependencies = append(relativeDependencies, strings.Split(absolutePath, g.root)[1])
+	}
+
+	// Make the relativeDependencies unique
+	relativeDependencies = uniqueStrings(relativeDependencies)
+	// Group by environment
+	relativeDependenciesGrouped, err := g.groupDependencies(relativeDependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	project := &DependencyDirs{
+		SourcePath:          relativeSourceDir,
+		ID:                  relativeSourceDir,
+		Dependencies:        relativeDependencies,
+		DependenciesGrouped: relativeDependenciesGrouped,
+	}
+	if locals.Environment != nil {
+		project.Environment = *locals.Environment
+	}
+	project.workspaces = locals.Workspaces
+
+	project.Meta, err = g.metadata(filepath.Dir(sourcePath))
+	if err != nil {
+		return nil, err
+	}
+
+	g.fileDependenciesMtx.Lock()
+	project.FileDependencies = g.fileDependencies[sourcePath]
+	g.fileDependenciesMtx.Unlock()
+
+	// The config was already parsed by `getDependencies`, so this comes from the store
+	parsedConfig, err := g.partialParseConfigFile(sourcePath, options, nil, dependenciesDecodeList)
+	if err != nil {
+		return nil, err
+	}
+	if parsedConfig.Terraform != nil && parsedConfig.Terraform.Source != nil {
+		project.Source, err = g.parseSource(*parsedConfig.Terraform.Source, sourcePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if terraformDir := g.terraformDir(sourcePath, project.Source); terraformDir != "" {
+		project.Terraform, err = g.terraformModule(terraformDir)
+		if err != nil {
+			return nil, err
+		}
+		g.recordStateReads(relativeSourceDir, project.Terraform.remoteStateReads)
+	}
+
+	// A remote state that can't be evaluated, for example because it calls a function that is not sandboxed, only
+	// leaves the state key empty
+	remoteState, err := g.remoteState(sourcePath, options)
+	if err != nil {
+		log.Warn("Could not evaluate the remote_state of ", sourcePath, ": ", err)
+	} else if remoteState != nil {
+		project.StateKey = stateKey(remoteState)
+		g.recordStateLocation(remoteState, relativeSourceDir, project.workspaces)
+	}
+
+	return project, nil
+}
+
+func groupByEnvironment(list []string) []EnvironmentGroup {
+	groups := make(map[string][]string)
+
+	for _, item := range list {
+		parts := strings.SplitN(item, "/", 2)
+		environment := "root"
+		if len(parts) > 1 {
+			environment = parts[0]
+		}
+		groups[environment] = append(groups[environment], item)
+	}
+
+	var result []EnvironmentGroup
+	for environment, items := range groups {
+		result = append(result, EnvironmentGroup{
+			Environment: environment,
+			Items:       items,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Environment < result[j].Environment
+	})
+
+	return result
+}
+
+// Finds the absolute paths of all terragrunt.hcl files under the root, skipping the directories and files ignored by
+// the ignore files
+func (g *Generator) getAllTerragruntFiles() ([]string, error) {
+	configFilePaths := []string{}
+	err := g.walkIgnoring(strings.TrimSuffix(g.root, string(filepath.Separator)), nil, func(dir string, rules ignoreRules) error {
+		configPaths := []string{config.GetDefaultConfigPath(dir)}
+		for _, name := range rootConfigNames {
+			configPaths = append(configPaths, filepath.Join(dir, name))
+		}
+		for _, configPath := range configPaths {
+			if util.FileExists(configPath) && !rules.ignored(g.relativePath(configPath), false) {
+				configFilePaths = append(configFilePaths, configPath)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	g.recordIncludedConfigs(configFilePaths)
+	return configFilePaths, nil
+}
+
+// recordIncludedConfigs records the configs included by the configs at `configPaths`, through every level, which are
+// parents whatever they contain
+func (g *Generator) recordIncludedConfigs(configPaths []string) {
+	for _, configPath := range configPaths {
+		terragruntOptions, err := g.newTerragruntOptions(configPath)
+		if err != nil {
+			continue
+		}
+		for _, includedPath := range g.nestedIncludePaths(configPath, terragruntOptions, nil) {
+			g.includedConfigs[includedPath] = true
+		}
+	}
+}
+
+// reset empties the caches and results of the previous run, so every run starts from what is on disk
+func (g *Generator) reset() {
+	g.store = newParsedFileStore()
+	g.getDependenciesCache = newGetDependenciesCache()
+	g.sandbox = newSandbox(g.opts.Sandbox, g.opts.SandboxValues)
+	g.edges = map[string][]string{}
+	g.edgeDetails = map[string][]Edge{}
+	g.stateLocations = map[string][]string{}
+	g.stateReads = map[string][]string{}
+	g.fileDependencies = map[string][]FileDependency{}
+	g.terraformModules = map[string]*TerraformModule{}
+	g.moduleCallNodes = map[string]*moduleCallNode{}
+	g.moduleCallSubtrees = map[string][]ModuleCall{}
+	g.includedConfigs = map[string]bool{}
+	g.metadataFiles = map[string]map[string]interface{}{}
+	g.metadataDirs = map[string]map[string]interface{}{}
+	g.closestFiles = map[string]string{}
+	g.matrixGenerators = map[string]*Generator{}
+	g.matrixProjects = map[string]map[string]bool{}
+	g.model = nil
+}
+
+// Collect finds every module under the root and the files each of them depends on. Collect can be called again to
+// pick up changes on disk, as nothing is cached between calls.
+func (g *Generator) Collect(ctx context.Context) (*Model, error) {
+	g.reset()
+
+	var strSlice = make([]DependencyDirs, 0)
+
+	lock := sync.Mutex{}
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	sem := semaphore.NewWeighted(g.opts.Parallelism)
+
+	// Concurrently looking all dependencies
+	log.Info("Working directory: ", g.root)
+	terragruntFiles, err := g.getAllTerragruntFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var exactEnvironmentRegexp *regexp.Regexp
+	if g.opts.Environment != "" {
+		exactEnvironmentRegexp, err = regexp.Compile(fmt.Sprint("/(", g.opts.Environment, ")/"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, terragruntPath := range terragruntFiles {
+		terragruntPath := terragruntPath // https://golang.org/doc/faq#closures_and_goroutines
+
+		if err := sem.Acquire(groupCtx, 1); err != nil {
+			break
+		}
+		errGroup.Go(func() error {
+			defer sem.Release(1)
+
+			// only run this check if the environment is given, else generate it for all
+			if exactEnvironmentRegexp != nil {
+				matched, err := g.inEnvironment(terragruntPath, exactEnvironmentRegexp)
+				if err != nil || !matched {
+					return err
+				}
+			}
+
+			projects, err := g.createProjects(terragruntPath)
+			if err != nil {
+				return err
+			}
+
+			if len(g.opts.Select) > 0 {
+				selected := []DependencyDirs{}
+				for _, project := range projects {
+					if project.selected(g.opts.Select) {
+						selected = append(selected, project)
+					}
+				}
+				projects = selected
+			}
+
+			// if there are no projects then skip this module
+			if len(projects) == 0 {
+				log.Debug("EMPTY Project at", terragruntPath)
+				return nil
+			}
+
+			// Lock the list as only one goroutine should be writing to config.Projects at a time
+			lock.Lock()
+			defer lock.Unlock()
+
+			log.Info("Collected dependencies for ", terragruntPath)
+			strSlice = append(strSlice, projects...)
+
+			return nil
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	g.mergeMatrixResults()
+	if err := g.checkStateLocations(); err != nil {
+		return nil, err
+	}
+
+	// The entries of a module keep the order of its workspaces
+	sort.SliceStable(strSlice, func(i, j int) bool {
+		return strSlice[i].SourcePath < strSlice[j].SourcePath
+	})
+
+	g.model = &Model{Modules: strSlice, Graph: g.buildGraph()}
+	return g.model, nil
+}
+
+// buildGraph converts the recorded edges between configs into edges between module directories. The entries of a
+// module with a matrix are nodes of their own, keyed by their ID
+func (g *Generator) buildGraph() Graph {
+	graph := Graph{Edges: map[string][]string{}, Inferred: map[string][]string{}, Details: map[string][]Edge{}}
+	entries := g.matrixEntries()
+	addEdges := func(node string, key string, dependencyConfigPaths []string, edges []Edge) {
+		dependencyNodes := []string{}
+		for _, dependencyConfigPath := range dependencyConfigPaths {
+			dependencyNodes = append(dependencyNodes, entries.nodes(g.relativeModuleDir(dependencyConfigPath), key)...)
+		}
+		sort.Strings(dependencyNodes)
+		graph.Edges[node] = uniqueStrings(dependencyNodes)
+
+		details := []Edge{}
+		for _, edge := range edges {
+			for _, dependencyNode := range entries.nodes(edge.Dependency, key) {
+				edge.Dependency = dependencyNode
+				details = append(details, edge)
+			}
+		}
+		sort.SliceStable(details, func(i, j int) bool {
+			return details[i].Dependency < details[j].Dependency
+		})
+		graph.Details[node] = details
+	}
+
+	for configPath, dependencyConfigPaths := range g.edges {
+		// The edges of a module with a matrix are the ones of its entries
+		if _, ok := entries[g.relativeModuleDir(configPath)]; !ok {
+			addEdges(g.relativeModuleDir(configPath), "", dependencyConfigPaths, g.edgeDetails[configPath])
+		}
+	}
+	for key, generator := range g.matrixGenerators {
+		for configPath := range g.matrixProjects[key] {
+			if dependencyConfigPaths, ok := generator.edges[configPath]; ok {
+				node := matrixEntryID(g.relativeModuleDir(configPath), key)
+				addEdges(node, key, dependencyConfigPaths, generator.edgeDetails[configPath])
+			}
+		}
+	}
+
+	for sourcePath, dependencyDirs := range g.inferredEdges() {
+		log.Info("Inferred that ", sourcePath, " depends on ", strings.Join(dependencyDirs, ", "), " from its terraform_remote_state data sources")
+		graph.Inferred[sourcePath] = dependencyDirs
+		edges := append(graph.Edges[sourcePath], dependencyDirs...)
+		sort.Strings(edges)
+		graph.Edges[sourcePath] = uniqueStrings(edges)
+
+		details := append([]Edge{}, graph.Details[sourcePath]...)
+		for _, dependencyDir := range dependencyDirs {
+			if !slices.ContainsFunc(details, func(edge Edge) bool { return edge.Dependency == dependencyDir }) {
+				details = append(details, Edge{Dependency: dependencyDir, Kind: EdgeKindInferred})
+			}
+		}
+		sort.SliceStable(details, func(i, j int) bool {
+			return details[i].Dependency < details[j].Dependency
+		})
+		graph.Details[sourcePath] = details
+	}
+	return graph
+}
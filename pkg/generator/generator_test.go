@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the output of the tests")
+
+func TestMain(m *testing.M) {
+	// Warnings about the fixtures are expected, and are checked through the output instead
+	log.SetLevel(log.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+// testPath returns the absolute path of `name` within the test directory of the repo
+func testPath(t *testing.T, name string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("..", "..", "test", filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkGolden compares `got` with the golden file `name` of test/golden, which -update rewrites
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := testPath(t, filepath.Join("golden", name))
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to write it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run the tests with -update to see how\ngot:\n%s", path, got)
+	}
+}
+
+// newTestGenerator returns a Generator over the `root` directory of test, with the config file found there, rendering
+// the test/inputs/dirs.tpl template. The sandbox is enabled, so fixtures never run commands
+func newTestGenerator(t *testing.T, root string, setOptions func(*Options)) *Generator {
+	t.Helper()
+	opts := DefaultOptions()
+	opts.Root = testPath(t, root)
+	opts.InputTemplate = testPath(t, "inputs/dirs.tpl")
+	opts.Sandbox = true
+	config, err := LoadConfig(filepath.Join(opts.Root, DefaultConfigPath), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Config = config
+	if setOptions != nil {
+		setOptions(&opts)
+	}
+
+	g, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		name       string
+		root       string
+		setOptions func(*Options)
+	}{
+		{name: "projects", root: "projects"},
+		{name: "environment", root: "projects", setOptions: func(opts *Options) {
+			opts.Environment = "prod"
+		}},
+		{name: "source_map_root", root: "projects/source_map", setOptions: func(opts *Options) {
+			opts.SourceMap = map[string]string{"git::ssh://git@github.com/example/infra.git": "."}
+		}},
+		{name: "matrix", root: "projects/matrix"},
+		{name: "remote_state", root: "projects/remote_state"},
+		{name: "metadata", root: "projects/metadata", setOptions: groupsTemplate},
+		{name: "metadata_environment", root: "projects/metadata", setOptions: func(opts *Options) {
+			groupsTemplate(opts)
+			opts.Environment = "prod"
+		}},
+		{name: "metadata_select", root: "projects/metadata", setOptions: func(opts *Options) {
+			groupsTemplate(opts)
+			opts.Select = map[string]string{"meta.environment": "prod", "meta.aws_region": "eu-west-1"}
+		}},
+		{name: "metadata_select_nested", root: "projects/metadata", setOptions: func(opts *Options) {
+			groupsTemplate(opts)
+			opts.Select = map[string]string{"meta.tags.tier": "staging"}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, tt.root, tt.setOptions)
+			if _, err := g.Collect(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := g.Render(&out); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "collect_"+tt.name+".txt", out.Bytes())
+		})
+	}
+}
+
+// groupsTemplate renders the test/inputs/groups.tpl template, which shows the metadata and dependency groups
+func groupsTemplate(opts *Options) {
+	opts.InputTemplate = filepath.Join(filepath.Dir(opts.InputTemplate), "groups.tpl")
+}
+
+func TestNewSelect(t *testing.T) {
+	tests := []struct {
+		selectors map[string]string
+		valid     bool
+	}{
+		{selectors: map[string]string{"meta.environment": "prod"}, valid: true},
+		{selectors: map[string]string{"meta.tags.tier": "production"}, valid: true},
+		{selectors: map[string]string{"environment": "prod"}, valid: false},
+		{selectors: map[string]string{"meta.": "prod"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.Select = tt.selectors
+		if _, err := New(opts); (err == nil) != tt.valid {
+			t.Errorf("New with select %v: got error %v, want valid %v", tt.selectors, err, tt.valid)
+		}
+	}
+}
+
+func TestFindings(t *testing.T) {
+	tests := []struct {
+		name string
+		root string
+		run  func(*Generator, context.Context) ([]Finding, error)
+	}{
+		{name: "validate", root: "validate", run: (*Generator).Validate},
+		{name: "lint", root: "lint", run: (*Generator).Lint},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, tt.root, nil)
+			findings, err := tt.run(g, context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := WriteFindings(&out, findings, FindingsFormatText); err != nil {
+				t.Fatal(err)
+			}
+			// Messages of the HCL parser have absolute paths
+			checkGolden(t, tt.name+".txt", bytes.ReplaceAll(out.Bytes(), []byte(g.root), nil))
+		})
+	}
+}
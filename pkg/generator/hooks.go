@@ -0,0 +1,214 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	log "github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FileDependency is a file of the repo that a hook or a `generate` block of a module refers to
+type FileDependency struct {
+	// Path of the file, relative to the root
+	Path string
+	// Block referring to the file, like `before_hook.validate` or `generate.provider`
+	Block string
+	// Config declaring the block, relative to the root. It is an included config when the block is inherited
+	Config string
+}
+
+var hookFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+		{Type: "generate", LabelNames: []string{"name"}},
+	},
+}
+
+var hookBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "before_hook", LabelNames: []string{"name"}},
+		{Type: "after_hook", LabelNames: []string{"name"}},
+	},
+}
+
+var hookAttributesSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "execute"},
+		{Name: "working_dir"},
+	},
+}
+
+var generateAttributesSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "contents"}},
+}
+
+// fileFunctions are the functions reading the file passed as their first argument
+var fileFunctions = []string{"file", "filebase64", "templatefile", "sops_decrypt_file"}
+
+// hookFiles returns the files of the repo that the hooks and `generate` blocks of the config at `path`, and of the
+// configs it includes, refer to. Hook arguments are files when they resolve to one, relative to the `working_dir` of the
+// hook or to the module directory. Within `generate` contents, the files read by `file()` and similar functions are.
+// Expressions that can't be evaluated, like the ones reading dependency outputs, are skipped
+func (g *Generator) hookFiles(
+	path string,
+	terragruntOptions *options.TerragruntOptions,
+	includeFromChild *config.IncludeConfig,
+	chain []string,
+) ([]FileDependency, error) {
+	parsed, err := g.store.file(path)
+	if err != nil {
+		return nil, err
+	}
+	localsAsCty, trackInclude, err := g.decodeBaseBlocks(terragruntOptions, parsed, path, includeFromChild, nil)
+	if err != nil {
+		return nil, err
+	}
+	evalContext, err := g.createEvalContext(path, terragruntOptions, config.EvalContextExtensions{
+		Locals:       localsAsCty,
+		TrackInclude: trackInclude,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	configDir := filepath.Dir(path)
+	moduleDir := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	files := []FileDependency{}
+	addFile := func(value string, baseDir string, block string) {
+		if file, ok := g.repoFile(value, baseDir); ok {
+			log.Debug("Depending on ", file, " from ", block, " in ", path)
+			files = append(files, FileDependency{Path: g.relativePath(file), Block: block, Config: g.relativePath(path)})
+		}
+	}
+
+	content, _, _ := parsed.file.Body.PartialContent(hookFileSchema)
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "terraform":
+			terraformContent, _, _ := block.Body.PartialContent(hookBlockSchema)
+			for _, hook := range terraformContent.Blocks {
+				name := hook.Type + "." + hook.Labels[0]
+				hookContent, _, _ := hook.Body.PartialContent(hookAttributesSchema)
+
+				baseDir := moduleDir
+				if attr, ok := hookContent.Attributes["working_dir"]; ok {
+					workingDir, ok := evaluateString(attr.Expr, evalContext, name, path)
+					if !ok {
+						continue
+					}
+					baseDir = workingDir
+					if !filepath.IsAbs(baseDir) {
+						baseDir = filepath.Join(configDir, baseDir)
+					}
+				}
+
+				attr, ok := hookContent.Attributes["execute"]
+				if !ok {
+					continue
+				}
+				execute, diags := attr.Expr.Value(evalContext)
+				if diags.HasErrors() {
+					log.Debug("Could not evaluate the execute attribute of ", name, " in ", path, ": ", diags)
+					continue
+				}
+				for _, argument := range stringList(execute) {
+					addFile(argument, baseDir, name)
+				}
+			}
+
+		case "generate":
+			name := block.Type + "." + block.Labels[0]
+			generateContent, _, _ := block.Body.PartialContent(generateAttributesSchema)
+			attr, ok := generateContent.Attributes["contents"]
+			if !ok {
+				continue
+			}
+			for _, argument := range fileFunctionArguments(attr.Expr) {
+				if file, ok := evaluateString(argument, evalContext, name, path); ok {
+					addFile(file, configDir, name)
+				}
+			}
+		}
+	}
+
+	chain = append(chain[:len(chain):len(chain)], path)
+	for _, include := range trackInclude.CurrentList {
+		includedPath := includePath(path, include)
+		if slices.Contains(chain, includedPath) {
+			continue
+		}
+		includedFiles, err := g.hookFiles(includedPath, terragruntOptions, &include, chain)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, includedFiles...)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// fileFunctionArguments returns the first argument of every call to one of the `fileFunctions` within `expr`. JSON
+// expressions have no function calls to look for
+func fileFunctionArguments(expr hcl.Expression) []hcl.Expression {
+	syntaxExpr, ok := expr.(hclsyntax.Expression)
+	if !ok {
+		return nil
+	}
+
+	arguments := []hcl.Expression{}
+	hclsyntax.VisitAll(syntaxExpr, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := node.(*hclsyntax.FunctionCallExpr)
+		if ok && slices.Contains(fileFunctions, call.Name) && len(call.Args) > 0 {
+			arguments = append(arguments, call.Args[0])
+		}
+		return nil
+	})
+	return arguments
+}
+
+// evaluateString evaluates `expr` to a string, logging why it can't be when it isn't one
+func evaluateString(expr hcl.Expression, evalContext *hcl.EvalContext, block string, path string) (string, bool) {
+	value, diags := expr.Value(evalContext)
+	if diags.HasErrors() {
+		log.Debug("Could not evaluate an argument of ", block, " in ", path, ": ", diags)
+		return "", false
+	}
+	if value.Type() != cty.String || !value.IsKnown() || value.IsNull() {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// repoFile returns the absolute path of `value` when it is a file within the root, relative paths being relative to
+// `baseDir`
+func (g *Generator) repoFile(value string, baseDir string) (string, bool) {
+	if value == "" || strings.Contains(value, "\n") {
+		return "", false
+	}
+	file := value
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(baseDir, file)
+	}
+	file = filepath.Clean(file)
+	// The root ends with a separator, so directories merely sharing its name as a prefix, like `<root>-other`, are not
+	// within it
+	if !strings.HasPrefix(file, g.root) {
+		return "", false
+	}
+	info, err := os.Stat(file)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return file, true
+}
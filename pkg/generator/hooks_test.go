@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHookFiles(t *testing.T) {
+	rootHook := FileDependency{Path: "scripts/validate.sh", Block: "before_hook.validate", Config: "root.hcl"}
+	rootTemplate := FileDependency{Path: "templates/provider.tf.tpl", Block: "generate.provider", Config: "root.hcl"}
+	tests := []struct {
+		module string
+		want   []FileDependency
+	}{
+		{module: "app", want: []FileDependency{
+			// Relative to the working_dir of the hook
+			{Path: "scripts/notify.sh", Block: "after_hook.notify", Config: "app/terragrunt.hcl"},
+			rootHook,
+			// Read through file()
+			{Path: "templates/backend.tf", Block: "generate.backend", Config: "app/terragrunt.hcl"},
+			// Read through templatefile(), relative to the included config
+			rootTemplate,
+		}},
+		// The plain-word arguments of its own hook are not files
+		{module: "db", want: []FileDependency{rootHook, rootTemplate}},
+	}
+
+	g := newTestGenerator(t, "projects/hooks", nil)
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			path := filepath.Join(g.root, tt.module, "terragrunt.hcl")
+			terragruntOptions, err := g.newTerragruntOptions(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := g.hookFiles(path, terragruntOptions, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"root/scripts/run.sh", "root-other/run.sh"} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := &Generator{root: filepath.Join(dir, "root") + string(filepath.Separator)}
+	baseDir := filepath.Join(dir, "root", "app")
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "../scripts/run.sh", want: filepath.Join(dir, "root", "scripts", "run.sh")},
+		{value: filepath.Join(dir, "root", "scripts", "run.sh"), want: filepath.Join(dir, "root", "scripts", "run.sh")},
+		// A directory
+		{value: "../scripts"},
+		{value: "fmt"},
+		{value: ""},
+		// Outside the root, even though its path starts with the one of the root
+		{value: "../../root-other/run.sh"},
+	}
+	for _, tt := range tests {
+		got, ok := g.repoFile(tt.value, baseDir)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("repoFile(%q): got %q, %v, want %q", tt.value, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package generator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Names of the ignore files read in every directory under the root
+const (
+	IgnoreFileName    = ".tgciignore"
+	gitignoreFileName = ".gitignore"
+)
+
+// alwaysIgnoredDirs are never walked, as they only hold copies of the code or git internals
+var alwaysIgnoredDirs = []string{".git", ".terragrunt-cache", ".terraform"}
+
+// ignoreRule is a line of an ignore file
+type ignoreRule struct {
+	// Directory of the ignore file, relative to the root with slashes. Empty for the root itself
+	base string
+	// Line as written, for logging
+	line string
+	// Ignore file the rule comes from
+	file string
+
+	pattern *regexp.Regexp
+	// When true, the pattern is matched against the whole path relative to `base` instead of the name only
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// ignoreRules are the rules applying to a directory, from the root down
+type ignoreRules []ignoreRule
+
+// parseIgnoreFile reads the rules of the ignore file at `file`, in the directory `base` relative to the root. A missing
+// file has no rules
+func parseIgnoreFile(file string, base string) (ignoreRules, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := ignoreRules{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rule, ok, err := parseIgnoreLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if ok {
+			rule.base = base
+			rule.file = file
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// parseIgnoreLine parses a line with gitignore syntax. False is returned for blank lines and comments
+func parseIgnoreLine(line string) (ignoreRule, bool, error) {
+	rule := ignoreRule{line: line}
+
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimSuffix(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return rule, false, nil
+	}
+
+	// A slash at the beginning or in the middle anchors the pattern to the directory of the ignore file
+	rule.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	pattern, err := regexp.Compile("^" + ignorePatternRegexp(line) + "$")
+	if err != nil {
+		return rule, false, fmt.Errorf("invalid pattern %q: %w", rule.line, err)
+	}
+	rule.pattern = pattern
+	return rule, true, nil
+}
+
+// ignorePatternRegexp translates a gitignore pattern into a regular expression
+func ignorePatternRegexp(pattern string) string {
+	var expr strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			// Leading or middle `**/` matches any number of directories, including none
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			// Trailing `**` matches everything inside
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String()
+}
+
+// match returns the rule deciding whether `relativePath`, relative to the root with slashes, is ignored. The last
+// matching rule wins, so nil is returned when no rule matches
+func (rules ignoreRules) match(relativePath string, isDir bool) *ignoreRule {
+	var matched *ignoreRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		pathFromBase := relativePath
+		if rule.base != "" {
+			if !strings.HasPrefix(relativePath, rule.base+"/") {
+				continue
+			}
+			pathFromBase = strings.TrimPrefix(relativePath, rule.base+"/")
+		}
+		if !rule.anchored {
+			pathFromBase = path.Base(pathFromBase)
+		}
+		if rule.pattern.MatchString(pathFromBase) {
+			matched = rule
+		}
+	}
+	return matched
+}
+
+// ignored tells whether `relativePath` is ignored by `rules`, logging the rule that decided it
+func (rules ignoreRules) ignored(relativePath string, isDir bool) bool {
+	rule := rules.match(relativePath, isDir)
+	if rule == nil || rule.negate {
+		return false
+	}
+	log.Debug("Ignoring ", relativePath, ", matched by ", rule.line, " in ", rule.file)
+	return true
+}
+
+// ignoreFileNames returns the names of the ignore files read in every directory
+func (g *Generator) ignoreFileNames() []string {
+	if g.opts.RespectGitignore {
+		return []string{gitignoreFileName, IgnoreFileName}
+	}
+	return []string{IgnoreFileName}
+}
+
+// walkIgnoring walks the directories under the root, skipping the ones the ignore files ignore, and calls `visit` with
+// each directory left along with the rules applying within it
+func (g *Generator) walkIgnoring(dir string, rules ignoreRules, visit func(dir string, rules ignoreRules) error) error {
+	relativeDir := strings.TrimSuffix(g.relativePath(dir+string(filepath.Separator)), "/")
+
+	// Rules of the directory come after the ones of its parents, so they take precedence
+	for _, name := range g.ignoreFileNames() {
+		dirRules, err := parseIgnoreFile(filepath.Join(dir, name), relativeDir)
+		if err != nil {
+			return err
+		}
+		rules = append(rules[:len(rules):len(rules)], dirRules...)
+	}
+
+	if err := visit(dir, rules); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || slices.Contains(alwaysIgnoredDirs, entry.Name()) {
+			continue
+		}
+		relativeEntry := path.Join(relativeDir, entry.Name())
+		if rules.ignored(relativeEntry, true) {
+			continue
+		}
+		if err := g.walkIgnoring(filepath.Join(dir, entry.Name()), rules, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// ignoreFile is an ignore file for rulesOf, in the directory `base` relative to the root
+type ignoreFile struct {
+	base  string
+	lines []string
+}
+
+// rulesOf parses the lines of `files`, given from the root down like walkIgnoring reads them
+func rulesOf(t *testing.T, files ...ignoreFile) ignoreRules {
+	t.Helper()
+	rules := ignoreRules{}
+	for _, file := range files {
+		for _, line := range file.lines {
+			rule, ok, err := parseIgnoreLine(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				rule.base = file.base
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []ignoreFile
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{name: "name anywhere", files: []ignoreFile{{lines: []string{"vendor"}}}, path: "live/vendor", isDir: true, ignored: true},
+		{name: "anchored", files: []ignoreFile{{lines: []string{"/vendor"}}}, path: "live/vendor", isDir: true, ignored: false},
+		{name: "anchored at the root", files: []ignoreFile{{lines: []string{"/vendor"}}}, path: "vendor", isDir: true, ignored: true},
+		{name: "slash in the middle anchors", files: []ignoreFile{{lines: []string{"live/vendor"}}}, path: "other/live/vendor", isDir: true, ignored: false},
+		{name: "directory only", files: []ignoreFile{{lines: []string{"scratch/"}}}, path: "scratch", isDir: false, ignored: false},
+		{name: "double star", files: []ignoreFile{{lines: []string{"**/examples/*"}}}, path: "live/eu/examples/demo", isDir: true, ignored: true},
+		{name: "trailing double star", files: []ignoreFile{{lines: []string{"archived/**"}}}, path: "archived/old/app", isDir: true, ignored: true},
+		{name: "character class", files: []ignoreFile{{lines: []string{"tmp[0-9]"}}}, path: "tmp1", isDir: true, ignored: true},
+		{name: "negated character class", files: []ignoreFile{{lines: []string{"tmp[!0-9]"}}}, path: "tmp1", isDir: true, ignored: false},
+		{name: "comment", files: []ignoreFile{{lines: []string{"# vendor"}}}, path: "# vendor", isDir: true, ignored: false},
+		{name: "escaped hash", files: []ignoreFile{{lines: []string{`\#vendor`}}}, path: "#vendor", isDir: true, ignored: true},
+		{name: "escaped bang", files: []ignoreFile{{lines: []string{`\!vendor`}}}, path: "!vendor", isDir: true, ignored: true},
+		{name: "trailing spaces", files: []ignoreFile{{lines: []string{"vendor  "}}}, path: "vendor", isDir: true, ignored: true},
+
+		// Negation and precedence: the last matching rule wins
+		{name: "negation", files: []ignoreFile{{lines: []string{"/archived/*", "!/archived/keep/"}}}, path: "archived/keep", isDir: true, ignored: false},
+		{name: "negation leaves the others", files: []ignoreFile{{lines: []string{"/archived/*", "!/archived/keep/"}}}, path: "archived/old", isDir: true, ignored: true},
+		{name: "negation first", files: []ignoreFile{{lines: []string{"!/archived/keep/", "/archived/*"}}}, path: "archived/keep", isDir: true, ignored: true},
+		{name: "nested file negates", files: []ignoreFile{
+			{lines: []string{"examples/"}},
+			{base: "live", lines: []string{"!examples/"}},
+		}, path: "live/examples", isDir: true, ignored: false},
+		{name: "nested file only applies below it", files: []ignoreFile{
+			{lines: []string{"examples/"}},
+			{base: "live", lines: []string{"!examples/"}},
+		}, path: "other/examples", isDir: true, ignored: true},
+		{name: "nested file ignores", files: []ignoreFile{
+			{lines: []string{"!examples/"}},
+			{base: "live", lines: []string{"examples/"}},
+		}, path: "live/examples", isDir: true, ignored: true},
+		// .tgciignore is read after .gitignore in the same directory
+		{name: "tgciignore negates gitignore", files: []ignoreFile{
+			{lines: []string{"scratch/"}},
+			{lines: []string{"!scratch/"}},
+		}, path: "scratch", isDir: true, ignored: false},
+		{name: "nested anchored to its directory", files: []ignoreFile{{base: "live", lines: []string{"/app"}}}, path: "live/app", isDir: true, ignored: true},
+		{name: "nested anchored not below", files: []ignoreFile{{base: "live", lines: []string{"/app"}}}, path: "live/eu/app", isDir: true, ignored: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ignored := rulesOf(t, tt.files...).ignored(tt.path, tt.isDir); ignored != tt.ignored {
+				t.Errorf("%s: got ignored %v, want %v", tt.path, ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestIgnoreFileNames(t *testing.T) {
+	g := newTestGenerator(t, "projects/ignore_files", func(opts *Options) {
+		opts.RespectGitignore = true
+	})
+	// Later files take precedence
+	if names := g.ignoreFileNames(); !reflect.DeepEqual(names, []string{gitignoreFileName, IgnoreFileName}) {
+		t.Errorf("got %v, want .gitignore then .tgciignore", names)
+	}
+}
+
+func TestIgnoreFiles(t *testing.T) {
+	tests := []struct {
+		name             string
+		respectGitignore bool
+		want             []string
+	}{
+		{
+			name: "tgciignore",
+			want: []string{"archived/keep/terragrunt.hcl", "live/app/terragrunt.hcl", "scratch/terragrunt.hcl"},
+		},
+		{
+			name:             "gitignore",
+			respectGitignore: true,
+			want:             []string{"archived/keep/terragrunt.hcl", "live/app/terragrunt.hcl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, "projects/ignore_files", func(opts *Options) {
+				opts.RespectGitignore = tt.respectGitignore
+			})
+			g.reset()
+			files, err := g.getAllTerragruntFiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, file := range files {
+				got = append(got, g.relativePath(file))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
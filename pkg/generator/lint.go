@@ -0,0 +1,358 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// Names of the lint rules
+const (
+	RuleGitRefTag                 = "git-ref-tag"
+	RuleRegistryVersion           = "registry-version"
+	RuleEnvironment               = "environment"
+	RuleAbsoluteExtraDependencies = "absolute-extra-dependencies"
+	RuleMaxDependencyDepth        = "max-dependency-depth"
+)
+
+// Default settings of the rules that have some
+const (
+	defaultTagPattern         = `^v?[0-9]+(\.[0-9]+)*([-+][0-9A-Za-z.-]+)?$`
+	defaultMaxDependencyDepth = 5
+)
+
+// lintModule is what the lint rules know about a module
+type lintModule struct {
+	// Absolute path of the config
+	path     string
+	includes []config.IncludeConfig
+	// Parsed `terraform.source` of the config. Nil when not set
+	source *Source
+	locals ResolvedLocals
+}
+
+// lintRule checks a module, reporting its findings to `l`
+type lintRule func(l *linter, module *lintModule, settings LintRule)
+
+var lintRules = map[string]lintRule{
+	RuleGitRefTag:                 lintGitRefTag,
+	RuleRegistryVersion:           lintRegistryVersion,
+	RuleEnvironment:               lintEnvironment,
+	RuleAbsoluteExtraDependencies: lintAbsoluteExtraDependencies,
+	RuleMaxDependencyDepth:        lintMaxDependencyDepth,
+}
+
+// linter gathers the findings of a Lint run
+type linter struct {
+	*validator
+
+	tagPattern *regexp.Regexp
+	// Memoized dependency depth of each config
+	depths map[string]int
+}
+
+func (l *linter) report(rule string, settings LintRule, path string, line int, format string, args ...interface{}) {
+	severity := settings.Severity
+	if severity == "" {
+		severity = SeverityWarning
+	}
+	l.add(rule, severity, path, line, format, args...)
+}
+
+// lintGitRefTag reports git sources that are not pinned to a tag
+func lintGitRefTag(l *linter, module *lintModule, settings LintRule) {
+	if module.source == nil || module.source.Type != SourceTypeGit {
+		return
+	}
+
+	file, line := l.locate(module.path, module.includes, "source", blockRef{Type: "terraform"})
+	if module.source.Ref == "" {
+		l.report(RuleGitRefTag, settings, file, line, "git source %s is not pinned to a ref", module.source.Raw)
+	} else if !l.tagPattern.MatchString(module.source.Ref) {
+		l.report(RuleGitRefTag, settings, file, line, "git source %s is pinned to %s, which is not a tag", module.source.Raw, module.source.Ref)
+	}
+}
+
+// lintRegistryVersion reports registry modules without a version, both as Terragrunt `tfr://` sources and as module
+// calls in the Terraform files of local sources
+func lintRegistryVersion(l *linter, module *lintModule, settings LintRule) {
+	terraformDirs := []string{filepath.Dir(module.path)}
+
+	if module.source != nil {
+		switch module.source.Type {
+		case SourceTypeRegistry:
+			if module.source.Ref == "" {
+				file, line := l.locate(module.path, module.includes, "source", blockRef{Type: "terraform"})
+				l.report(RuleRegistryVersion, settings, file, line, "registry source %s has no version", module.source.Raw)
+			}
+		case SourceTypeLocal:
+			terraformDirs = append(terraformDirs, filepath.Join(l.g.absoluteDir(module.source), module.source.Subdir))
+		}
+	}
+
+	for _, dir := range terraformDirs {
+		if !tfconfig.IsModuleDir(dir) {
+			continue
+		}
+		tfModule, _ := tfconfig.LoadModule(dir)
+		for _, call := range tfModule.ModuleCalls {
+			if !isRegistryModuleSource(call.Source) || call.Version != "" {
+				continue
+			}
+			l.report(RuleRegistryVersion, settings, call.Pos.Filename, call.Pos.Line, "module %q calls registry module %s without a version constraint", call.Name, call.Source)
+		}
+	}
+}
+
+// registryModuleSourcePattern matches `[hostname/]namespace/name/provider` registry addresses
+var registryModuleSourcePattern = regexp.MustCompile(`^([0-9A-Za-z.-]+/)?[0-9A-Za-z_-]+/[0-9A-Za-z_-]+/[0-9a-z]+(//.*)?$`)
+
+// isRegistryModuleSource tells whether the `source` of a Terraform module call is a registry address
+func isRegistryModuleSource(source string) bool {
+	if isLocalTerraformModuleSource(source) || strings.Contains(source, "::") || strings.Contains(source, "://") {
+		return false
+	}
+	return registryModuleSourcePattern.MatchString(source)
+}
+
+// lintEnvironment reports modules without environment, or with one that is not allowed. Like for `--environment`, the
+// environment of the metadata of a module comes first, then its `gitlab_ci_environment` local
+func lintEnvironment(l *linter, module *lintModule, settings LintRule) {
+	environment, file, ok, err := l.g.metadataEnvironment(module.path)
+	if err != nil {
+		l.addError(CheckParse, module.path, 0, err)
+		return
+	}
+	line := 0
+	if ok {
+		if stored, err := l.g.store.file(file); err == nil {
+			topKey, _, _ := strings.Cut(l.g.metadataEnvironmentKey(), ".")
+			line = findAttributeLine(stored.file, topKey, blockRef{Type: "locals"})
+		}
+	} else if module.locals.Environment != nil && *module.locals.Environment != "" {
+		environment = *module.locals.Environment
+		file, line = l.locate(module.path, module.includes, "gitlab_ci_environment", blockRef{Type: "locals"})
+	} else {
+		l.report(RuleEnvironment, settings, module.path, 0, "module %s has no environment in its metadata nor gitlab_ci_environment local", l.g.relativeModuleDir(module.path))
+		return
+	}
+
+	if len(settings.Allowed) > 0 && !slices.Contains(settings.Allowed, environment) {
+		l.report(RuleEnvironment, settings, file, line, "environment %s is not one of %s", environment, strings.Join(settings.Allowed, ", "))
+	}
+}
+
+// lintAbsoluteExtraDependencies reports extra dependencies outside of the root, from every local setting them. Paths
+// built with functions like `get_terragrunt_dir()` are absolute once evaluated, but stay within the root
+func lintAbsoluteExtraDependencies(l *linter, module *lintModule, settings LintRule) {
+	locals := []struct {
+		name         string
+		dependencies []string
+	}{
+		{name: "extra_atlantis_dependencies", dependencies: module.locals.ExtraGitlabCiDependencies},
+		{name: "gitlab_ci_extra_dependencies", dependencies: module.locals.ExtraDependencies},
+	}
+	for _, local := range locals {
+		for _, extraDependency := range local.dependencies {
+			if !filepath.IsAbs(extraDependency) || strings.HasPrefix(filepath.Clean(extraDependency), l.g.root) {
+				continue
+			}
+			file, line := l.locate(module.path, module.includes, local.name, blockRef{Type: "locals"})
+			l.report(RuleAbsoluteExtraDependencies, settings, file, line, "extra dependency %s is an absolute path", extraDependency)
+		}
+	}
+}
+
+// dependencyDepth returns the number of dependency levels below the config at `path`. Cycles are left to `validate`
+func (l *linter) dependencyDepth(path string, visiting map[string]bool) int {
+	if depth, ok := l.depths[path]; ok {
+		return depth
+	}
+	if visiting[path] {
+		return 0
+	}
+	visiting[path] = true
+	defer delete(visiting, path)
+
+	depth := 0
+	for _, dependencyPath := range l.g.edges[path] {
+		if dependencyDepth := l.dependencyDepth(dependencyPath, visiting) + 1; dependencyDepth > depth {
+			depth = dependencyDepth
+		}
+	}
+	l.depths[path] = depth
+	return depth
+}
+
+// lintMaxDependencyDepth reports modules with more dependency levels below them than allowed
+func lintMaxDependencyDepth(l *linter, module *lintModule, settings LintRule) {
+	maxDepth := settings.Max
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDependencyDepth
+	}
+
+	depth := l.dependencyDepth(module.path, map[string]bool{})
+	if depth <= maxDepth {
+		return
+	}
+
+	file, line := l.locate(module.path, module.includes, "config_path", blockRef{Type: "dependency"})
+	if line == 0 {
+		file, line = l.locate(module.path, module.includes, "paths", blockRef{Type: "dependencies"})
+	}
+	l.report(RuleMaxDependencyDepth, settings, file, line, "module %s has %d levels of dependencies, more than the %d allowed", l.g.relativeModuleDir(module.path), depth, maxDepth)
+}
+
+// collectLintModule parses what the lint rules need to know about the config at `path`. Nil is returned for parents,
+// ignored configs and configs that do not parse, which are reported
+func (l *linter) collectLintModule(path string) *lintModule {
+	g := l.g
+
+	terragruntOptions, err := g.newTerragruntOptions(path)
+	if err != nil {
+		l.addError(CheckParse, path, 0, err)
+		return nil
+	}
+	terragruntOptions.OriginalTerragruntConfigPath = path
+
+	role, includes, err := g.parseModule(path, terragruntOptions)
+	if err != nil {
+		l.addError(CheckParse, path, 0, err)
+		return nil
+	}
+	if role != roleModule {
+		return nil
+	}
+
+	decodeTypes := []config.PartialDecodeSectionType{
+		config.DependencyBlock,
+		config.DependenciesBlock,
+		config.TerraformSource,
+	}
+	parsedConfig, err := g.partialParseConfigFile(path, terragruntOptions, nil, decodeTypes)
+	if err != nil {
+		l.addError(CheckParse, path, 0, err)
+		return nil
+	}
+	if parsedConfig.Dependencies != nil {
+		g.recordEdges(path, parsedConfig.Dependencies.Paths)
+	}
+
+	locals, err := g.parseLocals(path, terragruntOptions, nil)
+	if err != nil {
+		l.addError(CheckParse, path, 0, err)
+		return nil
+	}
+
+	module := &lintModule{path: path, includes: includes, locals: locals}
+	if parsedConfig.Terraform != nil && parsedConfig.Terraform.Source != nil {
+		module.source, err = g.parseSource(*parsedConfig.Terraform.Source, path)
+		if err != nil {
+			l.addError(CheckParse, path, 0, err)
+			return nil
+		}
+	}
+	return module
+}
+
+// Lint runs the rules enabled in the config file against every module under the root. Configs that do not parse are
+// reported as errors, but `validate` is the command to find out why.
+//
+// The returned error is only set when the rules could not run.
+func (g *Generator) Lint(ctx context.Context) ([]Finding, error) {
+	enabledRules := []string{}
+	for name, settings := range g.opts.Config.Lint.Rules {
+		if _, ok := lintRules[name]; !ok {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		if settings.Severity != "" && settings.Severity != SeverityError && settings.Severity != SeverityWarning {
+			return nil, fmt.Errorf("lint rule %q has unknown severity %q, expected %s or %s", name, settings.Severity, SeverityError, SeverityWarning)
+		}
+		if settings.Enabled {
+			enabledRules = append(enabledRules, name)
+		}
+	}
+	sort.Strings(enabledRules)
+
+	tagPattern := defaultTagPattern
+	if pattern := g.opts.Config.Lint.Rules[RuleGitRefTag].Pattern; pattern != "" {
+		tagPattern = pattern
+	}
+	tagPatternRegexp, err := regexp.Compile(tagPattern)
+	if err != nil {
+		return nil, fmt.Errorf("lint rule %q has an invalid pattern: %w", RuleGitRefTag, err)
+	}
+
+	g.reset()
+	l := &linter{
+		validator:  &validator{g: g, findings: []Finding{}},
+		tagPattern: tagPatternRegexp,
+		depths:     map[string]int{},
+	}
+	if len(enabledRules) == 0 {
+		log.Warn("No lint rule is enabled in the config file")
+		return l.findings, nil
+	}
+
+	log.Info("Working directory: ", g.root)
+	terragruntFiles, err := g.getAllTerragruntFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	modules := []*lintModule{}
+	lock := sync.Mutex{}
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	sem := semaphore.NewWeighted(g.opts.Parallelism)
+	for _, terragruntPath := range terragruntFiles {
+		terragruntPath := terragruntPath // https://golang.org/doc/faq#closures_and_goroutines
+
+		if err := sem.Acquire(groupCtx, 1); err != nil {
+			break
+		}
+		errGroup.Go(func() error {
+			defer sem.Release(1)
+
+			module := l.collectLintModule(terragruntPath)
+			if module == nil {
+				return nil
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			modules = append(modules, module)
+			return nil
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].path < modules[j].path
+	})
+	for _, module := range modules {
+		for _, name := range enabledRules {
+			log.Debug("Running lint rule ", name, " on ", module.path)
+			lintRules[name](l, module, g.opts.Config.Lint.Rules[name])
+		}
+	}
+
+	// Findings in Terraform files shared by several modules are only reported once
+	sortFindings(l.findings)
+	return slices.Compact(l.findings), nil
+}
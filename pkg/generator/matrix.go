@@ -0,0 +1,288 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// configMatrix returns the environment variable sets of the `gitlab_ci_matrix` local of the config itself, and whether
+// it is set. The local is read without evaluation, as the module can only be evaluated under a set, so it must be a
+// literal list of objects
+func configMatrix(file *hcl.File) ([]map[string]string, bool, error) {
+	localsBlock, diags := getLocalsBlock(file)
+	if diags.HasErrors() || localsBlock == nil {
+		return nil, false, nil
+	}
+	attrs, _ := localsBlock.Body.JustAttributes()
+	attr, ok := attrs["gitlab_ci_matrix"]
+	if !ok {
+		return nil, false, nil
+	}
+
+	invalid := fmt.Errorf("%s: gitlab_ci_matrix must be a literal list of objects", attr.Range)
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.CanIterateElements() || value.Type().IsObjectType() || value.Type().IsMapType() {
+		return nil, false, invalid
+	}
+
+	sets := []map[string]string{}
+	it := value.ElementIterator()
+	for it.Next() {
+		_, element := it.Element()
+		if element.IsNull() || (!element.Type().IsObjectType() && !element.Type().IsMapType()) {
+			return nil, false, invalid
+		}
+		set := map[string]string{}
+		for name, variable := range element.AsValueMap() {
+			variable, err := convert.Convert(variable, cty.String)
+			if err != nil || variable.IsNull() {
+				return nil, false, fmt.Errorf("%s: gitlab_ci_matrix: %s must be a string", attr.Range, name)
+			}
+			set[name] = variable.AsString()
+		}
+		sets = append(sets, set)
+	}
+	return sets, true, nil
+}
+
+// matrixSets returns the environment variable sets the module at `path` is evaluated under: the ones of its
+// `gitlab_ci_matrix` local, or else the ones of the config file entries matching its directory, sorted by matrixKey.
+// None means the module is evaluated once, under the environment of the run
+func (g *Generator) matrixSets(path string) ([]map[string]string, error) {
+	parsed, err := g.store.file(path)
+	if err != nil {
+		return nil, err
+	}
+	sets, ok, err := configMatrix(parsed.file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		relativeDir := g.relativeModuleDir(path)
+		for _, entry := range g.opts.Config.Matrix {
+			matched, err := matrixEntryMatches(entry, relativeDir)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				sets = append(sets, entry.Env...)
+			}
+		}
+	}
+
+	unique := []map[string]string{}
+	seen := map[string]bool{}
+	for _, set := range sets {
+		if key := matrixKey(set); !seen[key] {
+			seen[key] = true
+			unique = append(unique, set)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		return matrixKey(unique[i]) < matrixKey(unique[j])
+	})
+	return unique, nil
+}
+
+// matrixEntryMatches tells whether one of the module globs of the config file `entry` matches `relativeDir`
+func matrixEntryMatches(entry MatrixEntry, relativeDir string) (bool, error) {
+	for _, pattern := range entry.Modules {
+		matcher, err := regexp.Compile("^" + ignorePatternRegexp(strings.Trim(pattern, "/")) + "$")
+		if err != nil {
+			return false, fmt.Errorf("config file: matrix: invalid pattern %q: %w", pattern, err)
+		}
+		if matcher.MatchString(relativeDir) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matrixKey returns the variables of `set` as a stable `NAME=value,...` string
+func matrixKey(set map[string]string) string {
+	variables := []string{}
+	for name, value := range set {
+		variables = append(variables, name+"="+value)
+	}
+	sort.Strings(variables)
+	return strings.Join(variables, ",")
+}
+
+// matrixEntryID returns the ID of the entry of the module in `sourcePath` under the matrix set `key`
+func matrixEntryID(sourcePath string, key string) string {
+	return sourcePath + "[" + key + "]"
+}
+
+// matrixEntryIDs are the IDs of the entries of the modules with a matrix, by source path and matrixKey
+type matrixEntryIDs map[string]map[string]string
+
+// matrixEntries returns the IDs of the entries the modules with a matrix got
+func (g *Generator) matrixEntries() matrixEntryIDs {
+	entries := matrixEntryIDs{}
+	for key, configPaths := range g.matrixProjects {
+		for configPath := range configPaths {
+			sourcePath := g.relativeModuleDir(configPath)
+			if entries[sourcePath] == nil {
+				entries[sourcePath] = map[string]string{}
+			}
+			entries[sourcePath][key] = matrixEntryID(sourcePath, key)
+		}
+	}
+	return entries
+}
+
+// nodes returns the graph nodes standing for the module in `sourcePath`, as depended on by an entry under the matrix
+// set `key`: the module itself when it has no matrix, its entry under the same set, or else all its entries, sorted
+func (e matrixEntryIDs) nodes(sourcePath string, key string) []string {
+	ids, ok := e[sourcePath]
+	if !ok {
+		return []string{sourcePath}
+	}
+	if id, ok := ids[key]; ok && key != "" {
+		return []string{id}
+	}
+	nodes := []string{}
+	for _, id := range ids {
+		nodes = append(nodes, id)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// matrixGenerator returns the Generator evaluating configs under the matrix `set`, on top of the environment of the run.
+// Every set has its own caches, shared by the modules evaluated under it, but the parsed files and their evaluations,
+// keyed by environment, are shared with the run
+func (g *Generator) matrixGenerator(set map[string]string) *Generator {
+	g.matrixMtx.Lock()
+	defer g.matrixMtx.Unlock()
+
+	key := matrixKey(set)
+	if generator, ok := g.matrixGenerators[key]; ok {
+		return generator
+	}
+
+	env := util.CloneStringMap(g.env)
+	for name, value := range set {
+		env[name] = value
+	}
+	generator := &Generator{opts: g.opts, root: g.root, env: env}
+	generator.reset()
+	// Calls to sandboxed functions are recorded along with the ones of the run
+	generator.sandbox = g.sandbox
+	generator.store = g.store
+	generator.includedConfigs = g.includedConfigs
+
+	g.matrixGenerators[key] = generator
+	return generator
+}
+
+// createProjects returns the entries of the module at `sourcePath`: one per matrix set the module is evaluated under,
+// or one for the module itself, each fanned out per workspace. An empty list means the module is skipped
+func (g *Generator) createProjects(sourcePath string) ([]DependencyDirs, error) {
+	sets, err := g.matrixSets(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sets) == 0 {
+		project, err := g.createProject(sourcePath)
+		if err != nil || project == nil {
+			return nil, err
+		}
+		return project.fanOut(), nil
+	}
+
+	entries := []DependencyDirs{}
+	for _, set := range sets {
+		key := matrixKey(set)
+		project, err := g.matrixGenerator(set).createProject(sourcePath)
+		if err != nil {
+			return nil, fmt.Errorf("matrix %s: %w", key, err)
+		}
+		if project == nil {
+			log.Debug("Skipping ", sourcePath, " under matrix ", key)
+			continue
+		}
+		g.recordMatrixProject(key, sourcePath)
+		project.ID = matrixEntryID(project.SourcePath, key)
+		project.Matrix = set
+		entries = append(entries, project.fanOut()...)
+	}
+	return entries, nil
+}
+
+// recordMatrixProject keeps track of the module at `sourcePath` getting an entry under the matrix set `key`
+func (g *Generator) recordMatrixProject(key string, sourcePath string) {
+	g.matrixMtx.Lock()
+	defer g.matrixMtx.Unlock()
+	if g.matrixProjects[key] == nil {
+		g.matrixProjects[key] = map[string]bool{}
+	}
+	g.matrixProjects[key][sourcePath] = true
+}
+
+// mergeMatrixResults adds what the evaluations under matrix sets recorded to the results of the run, so the graph and
+// the state location checks cover them, the states being recorded under the IDs of the entries. Only the edges of the modules that got an entry under a set are merged, as
+// the ones of a skipped set can point at modules that don't exist. The edges of the other modules evaluated under the
+// set, like its dependencies, are the ones of the run already
+func (g *Generator) mergeMatrixResults() {
+	keys := []string{}
+	for key := range g.matrixGenerators {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		generator := g.matrixGenerators[key]
+		for path, configPaths := range generator.edges {
+			if !g.matrixProjects[key][path] {
+				continue
+			}
+			g.edges[path] = uniqueStrings(append(append([]string{}, g.edges[path]...), configPaths...))
+		}
+		for path, edges := range generator.edgeDetails {
+			if !g.matrixProjects[key][path] {
+				continue
+			}
+			merged := append([]Edge{}, g.edgeDetails[path]...)
+			for _, edge := range edges {
+				if !containsEdge(merged, edge.Dependency) {
+					merged = append(merged, edge)
+				}
+			}
+			sort.SliceStable(merged, func(i, j int) bool {
+				return merged[i].Dependency < merged[j].Dependency
+			})
+			g.edgeDetails[path] = merged
+		}
+		// The states of the entries are told apart by their IDs
+		for location, sourcePaths := range generator.stateLocations {
+			for _, sourcePath := range sourcePaths {
+				g.stateLocations[location] = append(g.stateLocations[location], matrixEntryID(sourcePath, key))
+			}
+		}
+		for sourcePath, locations := range generator.stateReads {
+			id := matrixEntryID(sourcePath, key)
+			g.stateReads[id] = uniqueStrings(append(append([]string{}, g.stateReads[id]...), locations...))
+		}
+	}
+}
+
+// containsEdge tells whether `edges` has one to `dependency`
+func containsEdge(edges []Edge, dependency string) bool {
+	for _, edge := range edges {
+		if edge.Dependency == dependency {
+			return true
+		}
+	}
+	return false
+}
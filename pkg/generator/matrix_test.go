@@ -0,0 +1,34 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatrixEntryNodes(t *testing.T) {
+	entries := matrixEntryIDs{
+		"network": {
+			"REGION=us-east-1": matrixEntryID("network", "REGION=us-east-1"),
+			"REGION=eu-west-1": matrixEntryID("network", "REGION=eu-west-1"),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		sourcePath string
+		key        string
+		want       []string
+	}{
+		{name: "module without matrix", sourcePath: "dns", key: "REGION=us-east-1", want: []string{"dns"}},
+		{name: "entry under the same set", sourcePath: "network", key: "REGION=us-east-1", want: []string{"network[REGION=us-east-1]"}},
+		{name: "module without matrix depending on one", sourcePath: "network", want: []string{"network[REGION=eu-west-1]", "network[REGION=us-east-1]"}},
+		{name: "entry under another set", sourcePath: "network", key: "TENANT=acme", want: []string{"network[REGION=eu-west-1]", "network[REGION=us-east-1]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entries.nodes(tt.sourcePath, tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
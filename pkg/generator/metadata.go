@@ -0,0 +1,246 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// selectMetaPrefix starts the `--select` keys matching metadata locals
+const selectMetaPrefix = "meta."
+
+// defaultMetadataEnvironment is the metadata local holding the environment of a module, unless the config file says
+// otherwise
+const defaultMetadataEnvironment = "environment"
+
+// metadataFileNames returns the names of the metadata files to look up, from the most general to the most specific
+func (g *Generator) metadataFileNames() []string {
+	if g.opts.Config.Metadata.Files == nil {
+		return DefaultMetadataFiles
+	}
+	return g.opts.Config.Metadata.Files
+}
+
+// closestFile returns the closest file named `name`, looking up from `dir` to the root. Lookups are cached, as modules
+// of the same directory tree share their closest files
+func (g *Generator) closestFile(dir string, name string) (string, bool) {
+	cacheKey := filepath.Join(dir, name)
+	g.closestFilesMtx.Lock()
+	file, ok := g.closestFiles[cacheKey]
+	g.closestFilesMtx.Unlock()
+	if ok {
+		return file, file != ""
+	}
+
+	file = ""
+	for current := dir; strings.HasPrefix(current+string(filepath.Separator), g.root); current = filepath.Dir(current) {
+		if candidate := filepath.Join(current, name); util.FileExists(candidate) {
+			file = candidate
+			break
+		}
+		if current == filepath.Dir(current) {
+			break
+		}
+	}
+
+	g.closestFilesMtx.Lock()
+	defer g.closestFilesMtx.Unlock()
+	g.closestFiles[cacheKey] = file
+	return file, file != ""
+}
+
+// metadata returns the locals of the closest metadata files of the module in `dir`, merged from the most general file
+// to the most specific one. The result is shared by every caller for the same directory, so it must not be modified
+func (g *Generator) metadata(dir string) (map[string]interface{}, error) {
+	g.metadataMtx.Lock()
+	meta, ok := g.metadataDirs[dir]
+	g.metadataMtx.Unlock()
+	if ok {
+		return meta, nil
+	}
+
+	meta = map[string]interface{}{}
+	for _, name := range g.metadataFileNames() {
+		file, ok := g.closestFile(dir, name)
+		if !ok {
+			continue
+		}
+		locals, err := g.metadataLocals(file)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range locals {
+			meta[key] = value
+		}
+	}
+
+	g.metadataMtx.Lock()
+	defer g.metadataMtx.Unlock()
+	g.metadataDirs[dir] = meta
+	return meta, nil
+}
+
+// metadataLocals evaluates the locals of the metadata file at `path`, like `read_terragrunt_config` does. Files shared
+// by several modules are only evaluated once per run
+func (g *Generator) metadataLocals(path string) (map[string]interface{}, error) {
+	g.metadataMtx.Lock()
+	locals, ok := g.metadataFiles[path]
+	g.metadataMtx.Unlock()
+	if ok {
+		return locals, nil
+	}
+
+	terragruntOptions, err := g.newTerragruntOptions(path)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := g.store.file(path)
+	if err != nil {
+		return nil, err
+	}
+	localsAsCty, _, err := g.decodeBaseBlocks(terragruntOptions, parsed, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("metadata file %s: %w", path, err)
+	}
+	locals = map[string]interface{}{}
+	if localsAsCty != nil && *localsAsCty != cty.NilVal {
+		locals, err = parseCtyValueToMap(*localsAsCty)
+		if err != nil {
+			return nil, fmt.Errorf("metadata file %s: %w", path, err)
+		}
+	}
+
+	g.metadataMtx.Lock()
+	defer g.metadataMtx.Unlock()
+	g.metadataFiles[path] = locals
+	return locals, nil
+}
+
+// validateSelect checks the `--select` keys, which must name a metadata local
+func validateSelect(selectors map[string]string) error {
+	for key := range selectors {
+		if !strings.HasPrefix(key, selectMetaPrefix) || key == selectMetaPrefix {
+			return fmt.Errorf("select key %q must name a metadata local, like %senvironment", key, selectMetaPrefix)
+		}
+	}
+	return nil
+}
+
+// metaValue returns the metadata at the dotted path `key`, like `tags.team`, as a string
+func metaValue(meta map[string]interface{}, key string) (string, bool) {
+	var value interface{} = meta
+	for _, name := range strings.Split(key, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = object[name]; !ok || value == nil {
+			return "", false
+		}
+	}
+	return fmt.Sprint(value), true
+}
+
+// selected tells whether the metadata of the entry has every value of `selectors`. Keys are dotted paths within the
+// metadata, after the `meta.` prefix
+func (d DependencyDirs) selected(selectors map[string]string) bool {
+	for key, expected := range selectors {
+		if value, ok := metaValue(d.Meta, strings.TrimPrefix(key, selectMetaPrefix)); !ok || value != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataEnvironmentKey returns the dotted path of the metadata local holding the environment of a module
+func (g *Generator) metadataEnvironmentKey() string {
+	if g.opts.Config.Metadata.Environment == "" {
+		return defaultMetadataEnvironment
+	}
+	return g.opts.Config.Metadata.Environment
+}
+
+// metadataEnvironment returns the environment set by the metadata of the module of the config at `configPath`, along
+// with the metadata file setting it
+func (g *Generator) metadataEnvironment(configPath string) (environment string, file string, ok bool, err error) {
+	key := g.metadataEnvironmentKey()
+	topKey, _, _ := strings.Cut(key, ".")
+
+	// The most specific file setting the local wins, as metadata files are merged local by local
+	names := g.metadataFileNames()
+	for i := len(names) - 1; i >= 0; i-- {
+		file, found := g.closestFile(filepath.Dir(configPath), names[i])
+		if !found {
+			continue
+		}
+		locals, err := g.metadataLocals(file)
+		if err != nil {
+			return "", "", false, err
+		}
+		if _, found := locals[topKey]; found {
+			environment, ok := metaValue(locals, key)
+			return environment, file, ok, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// inEnvironment tells whether the module of the config at `configPath` is part of the `--environment` of the run: the
+// environment of its metadata when it has one, or else the directory of its path matching `environmentRegexp`
+func (g *Generator) inEnvironment(configPath string, environmentRegexp *regexp.Regexp) (bool, error) {
+	environment, _, ok, err := g.metadataEnvironment(configPath)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return environment == g.opts.Environment, nil
+	}
+	return environmentRegexp.MatchString(configPath), nil
+}
+
+// groupDependencies groups the `rules:changes` globs of a module by the `metadata.group_by` local of the config file,
+// looked up from the directory of each of them, or else by the first directory of their path
+func (g *Generator) groupDependencies(dependencies []string) ([]EnvironmentGroup, error) {
+	groupBy := g.opts.Config.Metadata.GroupBy
+	if groupBy == "" {
+		return groupByEnvironment(dependencies), nil
+	}
+
+	groups := map[string][]string{}
+	for _, dependency := range dependencies {
+		// The directory of a glob is the part before its first pattern
+		segments := strings.Split(dependency, "/")
+		literal := []string{}
+		for _, segment := range segments[:len(segments)-1] {
+			if strings.ContainsAny(segment, globMetaCharacters) {
+				break
+			}
+			literal = append(literal, segment)
+		}
+		meta, err := g.metadata(filepath.Join(g.root, filepath.FromSlash(path.Join(literal...))))
+		if err != nil {
+			return nil, err
+		}
+
+		group, ok := metaValue(meta, groupBy)
+		if !ok {
+			group = groupByEnvironment([]string{dependency})[0].Environment
+		}
+		groups[group] = append(groups[group], dependency)
+	}
+
+	result := []EnvironmentGroup{}
+	for group, items := range groups {
+		result = append(result, EnvironmentGroup{Environment: group, Items: items})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Environment < result[j].Environment
+	})
+	return result, nil
+}
@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetaValue(t *testing.T) {
+	meta := map[string]interface{}{
+		"environment": "prod",
+		"replicas":    2,
+		"disabled":    nil,
+		"tags": map[string]interface{}{
+			"tier":  "production",
+			"owner": map[string]interface{}{"team": "platform"},
+		},
+	}
+
+	tests := []struct {
+		key   string
+		want  string
+		found bool
+	}{
+		{key: "environment", want: "prod", found: true},
+		{key: "replicas", want: "2", found: true},
+		{key: "tags.tier", want: "production", found: true},
+		{key: "tags.owner.team", want: "platform", found: true},
+		{key: "missing"},
+		{key: "tags.missing"},
+		{key: "disabled"},
+		// Not an object to look into
+		{key: "environment.name"},
+		{key: "tags.tier.name"},
+		// Objects are formatted like fmt does
+		{key: "tags", want: "map[owner:map[team:platform] tier:production]", found: true},
+	}
+	for _, tt := range tests {
+		if got, found := metaValue(meta, tt.key); got != tt.want || found != tt.found {
+			t.Errorf("metaValue(%q): got %q, %v, want %q, %v", tt.key, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestValidateSelect(t *testing.T) {
+	err := validateSelect(map[string]string{"environment": "prod"})
+	if err == nil || !strings.Contains(err.Error(), `select key "environment" must name a metadata local, like meta.environment`) {
+		t.Errorf("got error %v, want one about the missing meta. prefix", err)
+	}
+	if err := validateSelect(map[string]string{"meta.tags.tier": "production"}); err != nil {
+		t.Errorf("got error %v, want none", err)
+	}
+}
+
+// The environment of the modules of test/projects/metadata is only set by their env.hcl
+func TestSelectThroughEnvFile(t *testing.T) {
+	g := newTestGenerator(t, "projects/metadata", func(opts *Options) {
+		opts.Select = map[string]string{"meta.environment": "staging"}
+	})
+	model, err := g.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, project := range model.Modules {
+		ids = append(ids, project.ID)
+	}
+	if want := []string{"stg/eu-west-1/app"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got modules %v, want %v", ids, want)
+	}
+
+	environment, file, ok, err := g.metadataEnvironment(filepath.Join(g.root, "stg", "eu-west-1", "app", "terragrunt.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(g.root, "stg", "env.hcl"); environment != "staging" || file != want || !ok {
+		t.Errorf("got environment %q from %s, %v, want staging from %s", environment, file, ok, want)
+	}
+}
+
+func TestMetadataCache(t *testing.T) {
+	g := newTestGenerator(t, "projects/metadata", nil)
+	dir := filepath.Join(g.root, "prd", "eu-west-1", "app")
+	meta, err := g.metadata(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := metaValue(meta, "aws_region"); value != "eu-west-1" {
+		t.Errorf("got aws_region %q, want eu-west-1", value)
+	}
+
+	// Every lookup was cached, including the ones finding nothing
+	for _, name := range g.metadataFileNames() {
+		if _, ok := g.closestFiles[filepath.Join(dir, name)]; !ok {
+			t.Errorf("closest %s of %s was not cached", name, dir)
+		}
+	}
+	again, err := g.metadata(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(again).Pointer() != reflect.ValueOf(meta).Pointer() {
+		t.Error("metadata was merged again")
+	}
+}
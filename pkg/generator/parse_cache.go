@@ -0,0 +1,210 @@
+package generator
+
+// Most modules in a Terragrunt repo share the same root config, and each module is looked at from several places
+// (`parseModule`, `parseLocals`, the partial parse of its blocks, and every cascaded dependency that points at it).
+// The store in this file makes sure every file is read and parsed once per run, and that the results derived from it
+// are only evaluated once.
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"golang.org/x/sync/singleflight"
+)
+
+// parsedFile is the AST of a single HCL file, along with the raw contents it was parsed from
+type parsedFile struct {
+	// Raw contents of the file
+	contents string
+	// Parser holding only this file, so diagnostics can be rendered against its source
+	parser *hclparse.Parser
+	// Parsed AST of the file
+	file *hcl.File
+}
+
+type parsedFileOutput struct {
+	parsed *parsedFile
+	err    error
+}
+
+type partialConfigOutput struct {
+	config *config.TerragruntConfig
+	err    error
+}
+
+type parseLocalsOutput struct {
+	locals ResolvedLocals
+	err    error
+}
+
+// parsedFileStore holds every file parsed during a run, as well as the evaluated results derived from them
+type parsedFileStore struct {
+	group singleflight.Group
+
+	mtx     sync.RWMutex
+	files   map[string]parsedFileOutput
+	configs map[string]partialConfigOutput
+	locals  map[string]parseLocalsOutput
+}
+
+func newParsedFileStore() *parsedFileStore {
+	return &parsedFileStore{
+		files:   map[string]parsedFileOutput{},
+		configs: map[string]partialConfigOutput{},
+		locals:  map[string]parseLocalsOutput{},
+	}
+}
+
+// file returns the parsed AST for `path`, reading and parsing it on first use
+func (s *parsedFileStore) file(path string) (*parsedFile, error) {
+	s.mtx.RLock()
+	cached, ok := s.files[path]
+	s.mtx.RUnlock()
+	if ok {
+		return cached.parsed, cached.err
+	}
+
+	res, err, _ := s.group.Do("file:"+path, func() (interface{}, error) {
+		s.mtx.RLock()
+		cached, ok := s.files[path]
+		s.mtx.RUnlock()
+		if ok {
+			return cached.parsed, cached.err
+		}
+
+		parsed, err := parseFile(path)
+
+		s.mtx.Lock()
+		s.files[path] = parsedFileOutput{parsed, err}
+		s.mtx.Unlock()
+
+		return parsed, err
+	})
+	if res == nil {
+		return nil, err
+	}
+	return res.(*parsedFile), err
+}
+
+// parseFile reads and parses a file for the store. Tests replace it to count how many times each file is parsed
+var parseFile = readAndParseFile
+
+func readAndParseFile(path string) (*parsedFile, error) {
+	configString, err := util.ReadFileAsString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	file, err := parseHcl(parser, configString, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsedFile{contents: configString, parser: parser, file: file}, nil
+}
+
+// partialParseConfigFile is a memoized version of `partialParseConfig`
+func (s *parsedFileStore) partialParseConfigFile(
+	path string,
+	terragruntOptions *options.TerragruntOptions,
+	include *config.IncludeConfig,
+	decodeList []config.PartialDecodeSectionType,
+	parse func() (*config.TerragruntConfig, error),
+) (*config.TerragruntConfig, error) {
+	key := fmt.Sprintf("%s|%s|%v", path, evaluationCacheKey(include, terragruntOptions), decodeList)
+
+	s.mtx.RLock()
+	cached, ok := s.configs[key]
+	s.mtx.RUnlock()
+	if ok {
+		return cached.config, cached.err
+	}
+
+	res, err, _ := s.group.Do("config:"+key, func() (interface{}, error) {
+		s.mtx.RLock()
+		cached, ok := s.configs[key]
+		s.mtx.RUnlock()
+		if ok {
+			return cached.config, cached.err
+		}
+
+		parsedConfig, err := parse()
+
+		s.mtx.Lock()
+		s.configs[key] = partialConfigOutput{parsedConfig, err}
+		s.mtx.Unlock()
+
+		return parsedConfig, err
+	})
+	if res == nil {
+		return nil, err
+	}
+	return res.(*config.TerragruntConfig), err
+}
+
+// parseLocals is a memoized version of `parseLocals`
+func (s *parsedFileStore) parseLocals(
+	path string,
+	terragruntOptions *options.TerragruntOptions,
+	includeFromChild *config.IncludeConfig,
+	parse func() (ResolvedLocals, error),
+) (ResolvedLocals, error) {
+	key := fmt.Sprintf("%s|%s", path, evaluationCacheKey(includeFromChild, terragruntOptions))
+
+	s.mtx.RLock()
+	cached, ok := s.locals[key]
+	s.mtx.RUnlock()
+	if ok {
+		return cached.locals, cached.err
+	}
+
+	res, err, _ := s.group.Do("locals:"+key, func() (interface{}, error) {
+		s.mtx.RLock()
+		cached, ok := s.locals[key]
+		s.mtx.RUnlock()
+		if ok {
+			return cached.locals, cached.err
+		}
+
+		locals, err := parse()
+
+		s.mtx.Lock()
+		s.locals[key] = parseLocalsOutput{locals, err}
+		s.mtx.Unlock()
+
+		return locals, err
+	})
+	if res == nil {
+		return ResolvedLocals{}, err
+	}
+	return res.(ResolvedLocals), err
+}
+
+// evaluationCacheKey identifies the context a file is evaluated in. The environment is part of it, as `get_env()` can
+// make a file evaluate differently, so Generators evaluating configs under other environments can share a store. A
+// parent config also evaluates differently for each child including it (`path_relative_to_include()`,
+// `get_original_terragrunt_dir()`, ...), so the child is part of the key.
+func evaluationCacheKey(include *config.IncludeConfig, terragruntOptions *options.TerragruntOptions) string {
+	names := make([]string, 0, len(terragruntOptions.Env))
+	for name := range terragruntOptions.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := fnv.New64a()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s=%s\x00", name, terragruntOptions.Env[name])
+	}
+
+	if include == nil {
+		return fmt.Sprintf("%x", hash.Sum64())
+	}
+	return fmt.Sprintf("%x|%s>%s", hash.Sum64(), terragruntOptions.TerragruntConfigPath, include.Path)
+}
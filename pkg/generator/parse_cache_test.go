@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStoreParsesOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		root string
+	}{
+		{name: "include chain", root: "projects/include_chain"},
+		// Modules of the matrix are evaluated by other Generators, sharing the store of the run
+		{name: "matrix", root: "projects/matrix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mtx sync.Mutex
+			parses := map[string]int{}
+			parseFile = func(path string) (*parsedFile, error) {
+				mtx.Lock()
+				parses[path]++
+				mtx.Unlock()
+				return readAndParseFile(path)
+			}
+			defer func() { parseFile = readAndParseFile }()
+
+			g := newTestGenerator(t, tt.root, nil)
+			if _, err := g.Collect(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			modules := 0
+			for path, count := range parses {
+				if count != 1 {
+					t.Errorf("%s parsed %d times, want once", strings.TrimPrefix(path, g.root), count)
+				}
+				if filepath.Base(path) == "terragrunt.hcl" {
+					modules++
+				}
+			}
+			if modules == 0 {
+				t.Errorf("no terragrunt.hcl went through the store, parsed %v", parses)
+			}
+			if len(g.store.configs) == 0 || len(g.store.locals) == 0 {
+				t.Errorf("partial parses and locals did not go through the store")
+			}
+		})
+	}
+}
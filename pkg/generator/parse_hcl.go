@@ -0,0 +1,304 @@
+package generator
+
+import (
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	log "github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+const bareIncludeKey = ""
+
+type parsedHcl struct {
+	Terraform *config.TerraformConfig `hcl:"terraform,block"`
+	Includes  []config.IncludeConfig  `hcl:"include,block"`
+}
+
+// terragruntIncludeMultiple is a struct that can be used to only decode the include block with labels.
+type terragruntIncludeMultiple struct {
+	Include []config.IncludeConfig `hcl:"include,block"`
+	Remain  hcl.Body               `hcl:",remain"`
+}
+
+// updateBareIncludeBlock searches the parsed terragrunt contents for a bare include block (include without a label),
+// and convert it to one with empty string as the label. This is necessary because the hcl parser is strictly enforces
+// label counts when parsing out labels with a go struct.
+//
+// Returns the updated contents, a boolean indicated whether anything changed, and an error (if any).
+func updateBareIncludeBlock(file *hcl.File, filename string) ([]byte, bool, error) {
+	hclFile, err := hclwrite.ParseConfig(file.Bytes, filename, hcl.InitialPos)
+	if err != nil {
+		return nil, false, errors.WithStackTrace(err)
+	}
+
+	codeWasUpdated := false
+	for _, block := range hclFile.Body().Blocks() {
+		if block.Type() == "include" && len(block.Labels()) == 0 {
+			if codeWasUpdated {
+				return nil, false, errors.WithStackTrace(config.MultipleBareIncludeBlocksErr{})
+			}
+			block.SetLabels([]string{bareIncludeKey})
+			codeWasUpdated = true
+		}
+	}
+	return hclFile.Bytes(), codeWasUpdated, nil
+}
+
+// decodeHcl uses the HCL2 parser to decode the parsed HCL into the struct specified by out.
+//
+// Note that we take a two pass approach to support parsing include blocks without a label. Ideally we can parse include
+// blocks with and without labels in a single pass, but the HCL parser is fairly restrictive when it comes to parsing
+// blocks with labels, requiring the exact number of expected labels in the parsing step.  To handle this restriction,
+// we first see if there are any include blocks without any labels, and if there is, we modify it in the file object to
+// inject the label as "".
+func (g *Generator) decodeHcl(
+	file *hcl.File,
+	filename string,
+	out interface{},
+	terragruntOptions *options.TerragruntOptions,
+	extensions config.EvalContextExtensions,
+) (err error) {
+	// The HCL2 parser and especially cty conversions will panic in many types of errors, so we have to recover from
+	// those panics here and convert them to normal errors
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = errors.WithStackTrace(config.PanicWhileParsingConfig{RecoveredValue: recovered, ConfigFile: filename})
+		}
+	}()
+
+	// Check if we need to update the file to label any bare include blocks.
+	// Excluding json because of https://github.com/transcend-io/terragrunt-atlantis-config/issues/244.
+	if filepath.Ext(filename) != ".json" {
+		updatedBytes, isUpdated, err := updateBareIncludeBlock(file, filename)
+		if err != nil {
+			return err
+		}
+		if isUpdated {
+			// Code was updated, so we need to reparse the new updated contents. This is necessarily because the blocks
+			// returned by hclparse does not support editing, and so we have to go through hclwrite, which leads to a
+			// different AST representation.
+			file, err = parseHcl(hclparse.NewParser(), string(updatedBytes), filename)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	evalContext, err := g.createEvalContext(filename, terragruntOptions, extensions)
+	if err != nil {
+		return err
+	}
+
+	decodeDiagnostics := gohcl.DecodeBody(file.Body, evalContext, out)
+	if decodeDiagnostics != nil && decodeDiagnostics.HasErrors() {
+		return decodeDiagnostics
+	}
+
+	return nil
+}
+
+// createEvalContext creates the EvalContext for the HCL2 parser with every function and variable Terragrunt offers, with
+// two differences to `config.CreateTerragruntEvalContext`:
+//   - exposed `include` blocks are parsed by this tool instead of by Terragrunt
+//   - the functions with side effects are replaced by stubs while the sandbox is enabled
+func (g *Generator) createEvalContext(
+	filename string,
+	terragruntOptions *options.TerragruntOptions,
+	extensions config.EvalContextExtensions,
+) (*hcl.EvalContext, error) {
+	trackInclude := extensions.TrackInclude
+	if trackInclude != nil && len(trackInclude.CurrentList) > 0 {
+		// Hide the `expose` attribute from Terragrunt so it doesn't parse the included configs on its own
+		hidden := *trackInclude
+		hidden.CurrentList = make([]config.IncludeConfig, len(trackInclude.CurrentList))
+		hidden.CurrentMap = make(map[string]config.IncludeConfig, len(trackInclude.CurrentMap))
+		for i, include := range trackInclude.CurrentList {
+			include.Expose = nil
+			hidden.CurrentList[i] = include
+			hidden.CurrentMap[include.Name] = include
+		}
+		extensions.TrackInclude = &hidden
+	}
+
+	evalContext, err := config.CreateTerragruntEvalContext(filename, terragruntOptions, extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	if trackInclude != nil && len(trackInclude.CurrentList) > 0 {
+		exposedInclude, err := g.includeMapAsCtyVal(filename, trackInclude.CurrentMap, terragruntOptions, extensions.PartialParseDecodeList)
+		if err != nil {
+			return nil, err
+		}
+		evalContext.Variables["include"] = exposedInclude
+	}
+
+	g.applySandbox(evalContext, filename, terragruntOptions)
+
+	return evalContext, nil
+}
+
+// This decodes only the `include` blocks of a terragrunt config, so its value can be used while decoding the rest of
+// the config.
+// For consistency, `include` in the call to `decodeHcl` is always assumed to be nil. Either it really is nil (parsing
+// the child config), or it shouldn't be used anyway (the parent config shouldn't have an include block).
+func (g *Generator) decodeAsTerragruntInclude(
+	file *hcl.File,
+	filename string,
+	terragruntOptions *options.TerragruntOptions,
+	extensions config.EvalContextExtensions,
+) ([]config.IncludeConfig, error) {
+	tgInc := terragruntIncludeMultiple{}
+	if err := g.decodeHcl(file, filename, &tgInc, terragruntOptions, extensions); err != nil {
+		return nil, err
+	}
+	return tgInc.Include, nil
+}
+
+// nestedIncludePaths returns the paths of the configs included by the included config at `path`, through every level.
+// `chain` holds the configs including `path`, to stop on include cycles
+func (g *Generator) nestedIncludePaths(path string, terragruntOptions *options.TerragruntOptions, chain []string) []string {
+	stored, err := g.store.file(path)
+	if err != nil {
+		return nil
+	}
+	includes, err := g.decodeAsTerragruntInclude(stored.file, path, terragruntOptions, config.EvalContextExtensions{})
+	if err != nil {
+		return nil
+	}
+
+	chain = append(chain[:len(chain):len(chain)], path)
+	paths := []string{}
+	for _, include := range includes {
+		nestedPath := includePath(path, include)
+		if slices.Contains(chain, nestedPath) {
+			continue
+		}
+		paths = append(paths, nestedPath)
+		paths = append(paths, g.nestedIncludePaths(nestedPath, terragruntOptions, chain)...)
+	}
+	return paths
+}
+
+// Roles a Terragrunt config can have
+const (
+	// A live module, which gets jobs
+	roleModule = "module"
+	// A config included by modules, which only matters as their dependency
+	roleParent = "parent"
+	// A config that is neither, like a shared `_envcommon` file named `terragrunt.hcl`
+	roleIgnore = "ignore"
+)
+
+// rootConfigNames are the names of root configs, which are parents whatever they contain. Unlike other parents, they
+// are looked for under the root like `terragrunt.hcl`, so they are parsed even when no module includes them
+var rootConfigNames = []string{"root.hcl", "root.hcl.json"}
+
+// configRole returns the role set by the `gitlab_ci_role` local of the config itself, or an empty string. The local is
+// read without evaluation, as includes are not resolved yet, so it must be a literal string
+func configRole(file *hcl.File) (string, error) {
+	localsBlock, diags := getLocalsBlock(file)
+	if diags.HasErrors() || localsBlock == nil {
+		return "", nil
+	}
+	attrs, _ := localsBlock.Body.JustAttributes()
+	attr, ok := attrs["gitlab_ci_role"]
+	if !ok {
+		return "", nil
+	}
+
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || value.Type() != cty.String || value.IsNull() {
+		return "", fmt.Errorf("%s: gitlab_ci_role must be a literal string", attr.Range)
+	}
+	role := value.AsString()
+	if role != roleModule && role != roleParent && role != roleIgnore {
+		return "", fmt.Errorf("%s: gitlab_ci_role must be %s, %s or %s, got %q", attr.Range, roleModule, roleParent, roleIgnore, role)
+	}
+	return role, nil
+}
+
+// parseModule classifies the config at `path` as a module, a parent or a file to ignore, and returns the configs it
+// includes. The first of these wins:
+//   - the `gitlab_ci_role` local
+//   - a config included by other configs is a parent
+//   - a root config, named like `root.hcl`, is a parent
+//   - a config with an `include` block is a module
+//   - a config with a terraform source is a module
+//   - a config next to `.tf` files is a module, as Terragrunt runs them
+//
+// Anything else is likely a parent (though not guaranteed)
+func (g *Generator) parseModule(path string, terragruntOptions *options.TerragruntOptions) (role string, includes []config.IncludeConfig, err error) {
+	stored, err := g.store.file(path)
+	if err != nil {
+		return "", nil, err
+	}
+	file := stored.file
+
+	role, err = configRole(file)
+	if err != nil {
+		return "", nil, err
+	}
+	if role == roleIgnore {
+		log.Debug("Classified ", path, " as ", role, ": set by gitlab_ci_role")
+		return role, nil, nil
+	}
+
+	// Decode just the `include` and `import` blocks, and verify that it's allowed here
+	extensions := config.EvalContextExtensions{}
+	terragruntIncludeList, err := g.decodeAsTerragruntInclude(file, path, terragruntOptions, extensions)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var reason string
+	role, reason = g.classifyModule(path, role, file, terragruntIncludeList, terragruntOptions, extensions)
+	log.Debug("Classified ", path, " as ", role, ": ", reason)
+	return role, terragruntIncludeList, nil
+}
+
+// classifyModule returns the role of the config at `path`, and why, given the role set by its `gitlab_ci_role` local
+func (g *Generator) classifyModule(
+	path string,
+	role string,
+	file *hcl.File,
+	includes []config.IncludeConfig,
+	terragruntOptions *options.TerragruntOptions,
+	extensions config.EvalContextExtensions,
+) (string, string) {
+	if role != "" {
+		return role, "set by gitlab_ci_role"
+	}
+	if g.includedConfigs[path] {
+		return roleParent, "included by other configs"
+	}
+	if slices.Contains(rootConfigNames, filepath.Base(path)) {
+		return roleParent, "named like a root config"
+	}
+	if len(includes) > 0 {
+		return roleModule, "has include blocks"
+	}
+
+	// We don't need to check the errors/diagnostics coming from `decodeHcl`, as when errors come up,
+	// it will leave the partially parsed result in the output object.
+	var parsed parsedHcl
+	g.decodeHcl(file, path, &parsed, terragruntOptions, extensions)
+	if parsed.Terraform != nil && parsed.Terraform.Source != nil {
+		return roleModule, "has a terraform source"
+	}
+	if tfconfig.IsModuleDir(filepath.Dir(path)) {
+		return roleModule, "has .tf files next to it"
+	}
+
+	return roleParent, "has no include block, terraform source nor .tf files"
+}
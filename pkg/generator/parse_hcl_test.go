@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestClassification(t *testing.T) {
+	g := newTestGenerator(t, "projects/classification", nil)
+	g.reset()
+	files, err := g.getAllTerragruntFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roles := map[string]string{}
+	for _, path := range files {
+		terragruntOptions, err := g.newTerragruntOptions(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		role, _, err := g.parseModule(path, terragruntOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roles[g.relativePath(path)] = role
+	}
+
+	tests := []struct {
+		path string
+		role string
+	}{
+		// Root config included by app
+		{path: "root.hcl", role: roleParent},
+		// Root config included by no module, found all the same
+		{path: "standalone/root.hcl", role: roleParent},
+		// Has a terraform source, but is included by consumer
+		{path: "shared/terragrunt.hcl", role: roleParent},
+		{path: "consumer/terragrunt.hcl", role: roleModule},
+		{path: "app/terragrunt.hcl", role: roleModule},
+		{path: "terraform_only/terragrunt.hcl", role: roleModule},
+		// gitlab_ci_role wins over everything else
+		{path: "forced_module/terragrunt.hcl", role: roleModule},
+		{path: "_envcommon/vpc/terragrunt.hcl", role: roleIgnore},
+	}
+	for _, tt := range tests {
+		role, ok := roles[filepath.ToSlash(tt.path)]
+		if !ok {
+			t.Errorf("%s was not found, got %v", tt.path, roles)
+			continue
+		}
+		if role != tt.role {
+			t.Errorf("%s: got role %s, want %s", tt.path, role, tt.role)
+		}
+	}
+	if len(roles) != len(tests) {
+		t.Errorf("got configs %v, want %d", roles, len(tests))
+	}
+}
@@ -0,0 +1,665 @@
+package generator
+
+// Terragrunt doesn't give us an easy way to access all of the Locals from a module
+// in an easy to digest way. This file is mostly just follows along how Terragrunt
+// parses the `locals` blocks and evaluates their contents.
+
+import (
+	"github.com/gruntwork-io/go-commons/errors"
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ResolvedLocals are the parsed result of local values this module cares about
+type ResolvedLocals struct {
+	// Extra dependencies that can be hardcoded in config
+	ExtraGitlabCiDependencies []string
+
+	// Extra dependencies of the `gitlab_ci_extra_dependencies` local, which can be globs or directories and must match
+	// at least one file
+	ExtraDependencies []string
+
+	// Patterns of the files within the module directory whose changes do not trigger its jobs
+	IgnoreChanges []string
+
+	// Dependencies of the `gitlab_ci_ignore_dependencies` local, which is either a list ignored for both change
+	// tracking and cascading, or an object with `changes` and `cascade` lists
+	IgnoreDependencies IgnoreDependencies
+
+	// Terraform workspaces of the `gitlab_ci_workspaces` local, each of which gets its own jobs
+	Workspaces []Workspace
+
+	// If set to true, the module will not be included in the output
+	Skip *bool
+
+	// Environment, or GitLab deployment tier, the module belongs to
+	Environment *string
+
+	// Settings of this tool set by the config and the configs it includes, merged, by local name
+	settings map[string]cty.Value
+	// Merge behaviours declared by the config itself, by local name
+	mergeBehaviours map[string]string
+	// Why the locals of the configs it includes, through every level, could not be evaluated, by absolute path. Their
+	// settings are not inherited
+	uninheritedParents map[string]error
+}
+
+// parseHcl uses the HCL2 parser to parse the given string into an HCL file body.
+func parseHcl(parser *hclparse.Parser, hcl string, filename string) (file *hcl.File, err error) {
+	// The HCL2 parser and especially cty conversions will panic in many types of errors, so we have to recover from
+	// those panics here and convert them to normal errors
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = errors.WithStackTrace(config.PanicWhileParsingConfig{RecoveredValue: recovered, ConfigFile: filename})
+		}
+	}()
+
+	if filepath.Ext(filename) == ".json" {
+		file, parseDiagnostics := parser.ParseJSON([]byte(hcl), filename)
+		if parseDiagnostics != nil && parseDiagnostics.HasErrors() {
+			return nil, parseDiagnostics
+		}
+
+		return file, nil
+	}
+
+	file, parseDiagnostics := parser.ParseHCL([]byte(hcl), filename)
+	if parseDiagnostics != nil && parseDiagnostics.HasErrors() {
+		return nil, parseDiagnostics
+	}
+
+	return file, nil
+}
+
+// Merge behaviours a config can declare for its locals in the `gitlab_ci_merge` local
+const (
+	// Lists are appended to the inherited ones and maps merged into them, key by key. Anything else is replaced
+	MergeAppend = "append"
+	// The inherited value is replaced
+	MergeReplace = "replace"
+	// Like append, but maps found under the same key are merged recursively
+	MergeDeepMerge = "deep-merge"
+)
+
+// isInheritedLocal tells whether the local `name` is a setting of this tool, inherited by the configs including the one
+// setting it. `gitlab_ci_role` only applies to the config setting it
+func isInheritedLocal(name string) bool {
+	switch name {
+	case "gitlab_cicd_skip", "extra_atlantis_dependencies":
+		return true
+	case "gitlab_ci_role", "gitlab_ci_merge", "gitlab_ci_matrix":
+		return false
+	}
+	return strings.HasPrefix(name, "gitlab_ci_")
+}
+
+// inheritedLocals returns the settings of `localsAsCty`, along with the merge behaviour declared for each of them
+func inheritedLocals(path string, localsAsCty cty.Value) (map[string]cty.Value, map[string]string, error) {
+	settings := map[string]cty.Value{}
+	behaviours := map[string]string{}
+	if localsAsCty == cty.NilVal {
+		return settings, behaviours, nil
+	}
+
+	for name, value := range localsAsCty.AsValueMap() {
+		if isInheritedLocal(name) {
+			settings[name] = value
+		}
+	}
+
+	mergeValue, ok := localsAsCty.AsValueMap()["gitlab_ci_merge"]
+	if !ok || mergeValue.IsNull() {
+		return settings, behaviours, nil
+	}
+	if !mergeValue.IsKnown() || !(mergeValue.Type().IsObjectType() || mergeValue.Type().IsMapType()) {
+		return nil, nil, fmt.Errorf("%s: gitlab_ci_merge must be a map of local names to merge behaviours", path)
+	}
+	for name, behaviourValue := range mergeValue.AsValueMap() {
+		behaviour := ""
+		if behaviourValue.Type() == cty.String && behaviourValue.IsKnown() && !behaviourValue.IsNull() {
+			behaviour = behaviourValue.AsString()
+		}
+		if behaviour != MergeAppend && behaviour != MergeReplace && behaviour != MergeDeepMerge {
+			return nil, nil, fmt.Errorf("%s: gitlab_ci_merge: merge behaviour of %s must be %s, %s or %s", path, name, MergeAppend, MergeReplace, MergeDeepMerge)
+		}
+		behaviours[name] = behaviour
+	}
+	return settings, behaviours, nil
+}
+
+// mergeInheritedLocals merges the `child` settings into the inherited `parent` ones, following the merge behaviours
+// declared by the child. The default is to append
+func mergeInheritedLocals(parent map[string]cty.Value, child map[string]cty.Value, behaviours map[string]string) map[string]cty.Value {
+	merged := make(map[string]cty.Value, len(parent)+len(child))
+	for name, value := range parent {
+		merged[name] = value
+	}
+	for name, value := range child {
+		inherited, ok := merged[name]
+		if !ok {
+			merged[name] = value
+			continue
+		}
+		behaviour := behaviours[name]
+		if behaviour == "" {
+			behaviour = MergeAppend
+		}
+		merged[name] = mergeLocalValue(inherited, value, behaviour)
+	}
+	return merged
+}
+
+// mergeLocalValue merges the `child` value of a local into the inherited `parent` one
+func mergeLocalValue(parent cty.Value, child cty.Value, behaviour string) cty.Value {
+	if behaviour == MergeReplace || !parent.IsKnown() || !child.IsKnown() || parent.IsNull() || child.IsNull() {
+		return child
+	}
+
+	parentType, childType := parent.Type(), child.Type()
+	isList := func(t cty.Type) bool { return t.IsListType() || t.IsTupleType() || t.IsSetType() }
+	isMap := func(t cty.Type) bool { return t.IsMapType() || t.IsObjectType() }
+
+	switch {
+	case isList(parentType) && isList(childType):
+		elements := []cty.Value{}
+		for _, value := range []cty.Value{parent, child} {
+			it := value.ElementIterator()
+			for it.Next() {
+				_, element := it.Element()
+				elements = append(elements, element)
+			}
+		}
+		return cty.TupleVal(elements)
+	case isMap(parentType) && isMap(childType):
+		attributes := parent.AsValueMap()
+		if attributes == nil {
+			attributes = map[string]cty.Value{}
+		}
+		for key, value := range child.AsValueMap() {
+			if inherited, ok := attributes[key]; ok && behaviour == MergeDeepMerge {
+				value = mergeLocalValue(inherited, value, behaviour)
+			}
+			attributes[key] = value
+		}
+		return cty.ObjectVal(attributes)
+	}
+	return child
+}
+
+// Parses a given file, returning a map of all it's `local` values
+func (g *Generator) parseLocals(path string, terragruntOptions *options.TerragruntOptions, includeFromChild *config.IncludeConfig) (ResolvedLocals, error) {
+	return g.parseIncludedLocals(path, terragruntOptions, includeFromChild, nil)
+}
+
+// parseIncludedLocals is parseLocals for a config included through `chain`, the configs including it from the child
+// down, which stops on include cycles
+func (g *Generator) parseIncludedLocals(
+	path string,
+	terragruntOptions *options.TerragruntOptions,
+	includeFromChild *config.IncludeConfig,
+	chain []string,
+) (ResolvedLocals, error) {
+	if slices.Contains(chain, path) {
+		return ResolvedLocals{}, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), path)
+	}
+	return g.store.parseLocals(path, terragruntOptions, includeFromChild, func() (ResolvedLocals, error) {
+		return g.evaluateLocals(path, terragruntOptions, includeFromChild, append(chain[:len(chain):len(chain)], path))
+	})
+}
+
+// Evaluates the `locals` of a given file and of the files it includes through every level, without going through the
+// store. Includes are merged in the order Terragrunt merges them: every included config, in order, then the config
+// itself
+func (g *Generator) evaluateLocals(path string, terragruntOptions *options.TerragruntOptions, includeFromChild *config.IncludeConfig, chain []string) (ResolvedLocals, error) {
+	// Get the HCL AST body of the file
+	parsed, err := g.store.file(path)
+	if err != nil {
+		return ResolvedLocals{}, err
+	}
+
+	// Decode just the Base blocks. See the function docs for decodeBaseBlocks for more info on what base blocks are.
+	localsAsCty, trackInclude, err := g.decodeBaseBlocks(terragruntOptions, parsed, path, includeFromChild, nil)
+	if err != nil {
+		return ResolvedLocals{}, err
+	}
+
+	// Recurse on the parents to merge in the locals from those files. Parents that can't be evaluated on their own are
+	// left out, which `validate` reports
+	inherited := map[string]cty.Value{}
+	uninheritedParents := map[string]error{}
+	if trackInclude != nil {
+		for _, includeConfig := range trackInclude.CurrentList {
+			includeConfig.Path = includePath(path, includeConfig)
+			parentLocals, err := g.parseIncludedLocals(includeConfig.Path, terragruntOptions, &includeConfig, chain)
+			if err != nil {
+				log.Warn("Could not evaluate the locals of ", includeConfig.Path, " included by ", path, ", its settings are not inherited: ", err)
+				uninheritedParents[includeConfig.Path] = err
+				continue
+			}
+			inherited = mergeInheritedLocals(inherited, parentLocals.settings, parentLocals.mergeBehaviours)
+			for parentPath, err := range parentLocals.uninheritedParents {
+				uninheritedParents[parentPath] = err
+			}
+		}
+	}
+
+	settings, behaviours, err := inheritedLocals(path, *localsAsCty)
+	if err != nil {
+		return ResolvedLocals{}, err
+	}
+	merged := mergeInheritedLocals(inherited, settings, behaviours)
+	mergedAsCty, err := convertValuesMapToCtyVal(merged)
+	if err != nil {
+		return ResolvedLocals{}, err
+	}
+
+	resolved := resolveLocals(mergedAsCty)
+	resolved.settings = merged
+	resolved.mergeBehaviours = behaviours
+	resolved.uninheritedParents = uninheritedParents
+	return resolved, nil
+}
+
+func resolveLocals(localsAsCty cty.Value) ResolvedLocals {
+	resolved := ResolvedLocals{}
+
+	// Return an empty set of locals if no `locals` block was present
+	if localsAsCty == cty.NilVal {
+		return resolved
+	}
+	rawLocals := localsAsCty.AsValueMap()
+
+	// If the `gitlab_cicd_skip` local is set to true, we should skip this module.
+	skipValue, ok := rawLocals["gitlab_cicd_skip"]
+	if ok {
+		hasValue := skipValue.True()
+		resolved.Skip = &hasValue
+	}
+
+	environmentValue, ok := rawLocals["gitlab_ci_environment"]
+	if ok && environmentValue.Type() == cty.String && environmentValue.IsKnown() && !environmentValue.IsNull() {
+		environment := environmentValue.AsString()
+		resolved.Environment = &environment
+	}
+
+	extraDependenciesAsCty, ok := rawLocals["extra_atlantis_dependencies"]
+	if ok {
+		it := extraDependenciesAsCty.ElementIterator()
+		for it.Next() {
+			_, val := it.Element()
+			resolved.ExtraGitlabCiDependencies = append(
+				resolved.ExtraGitlabCiDependencies,
+				filepath.ToSlash(val.AsString()),
+			)
+		}
+	}
+
+	if value, ok := rawLocals["gitlab_ci_extra_dependencies"]; ok {
+		for _, extraDependency := range stringList(value) {
+			resolved.ExtraDependencies = append(resolved.ExtraDependencies, filepath.ToSlash(extraDependency))
+		}
+	}
+
+	if value, ok := rawLocals["gitlab_ci_ignore_changes"]; ok {
+		resolved.IgnoreChanges = stringList(value)
+	}
+
+	if value, ok := rawLocals["gitlab_ci_workspaces"]; ok {
+		resolved.Workspaces = parseWorkspaces(value)
+	}
+
+	if value, ok := rawLocals["gitlab_ci_ignore_dependencies"]; ok && value.IsKnown() && !value.IsNull() {
+		if value.Type().IsObjectType() || value.Type().IsMapType() {
+			settings := value.AsValueMap()
+			if changes, ok := settings["changes"]; ok {
+				resolved.IgnoreDependencies.Changes = stringList(changes)
+			}
+			if cascade, ok := settings["cascade"]; ok {
+				resolved.IgnoreDependencies.Cascade = stringList(cascade)
+			}
+		} else {
+			resolved.IgnoreDependencies.Changes = stringList(value)
+			resolved.IgnoreDependencies.Cascade = stringList(value)
+		}
+	}
+
+	return resolved
+}
+
+// stringList returns the known strings of a list, set or tuple local. Anything else is left out
+func stringList(value cty.Value) []string {
+	list := []string{}
+	if !value.IsKnown() || value.IsNull() || !value.CanIterateElements() {
+		return list
+	}
+	it := value.ElementIterator()
+	for it.Next() {
+		_, element := it.Element()
+		if element.Type() == cty.String && element.IsKnown() && !element.IsNull() {
+			list = append(list, element.AsString())
+		}
+	}
+	return list
+}
+
+// decodeBaseBlocks takes a parsed HCL2 file and decodes the base blocks, like `config.DecodeBaseBlocks` does. Base
+// blocks are blocks that should always be decoded even in partial decoding, because they provide bindings that are
+// necessary for parsing any block in the file. Currently base blocks are:
+// - locals
+// - include
+//
+// Unlike Terragrunt's version, all expressions are evaluated through `createEvalContext`, so the sandbox applies.
+func (g *Generator) decodeBaseBlocks(
+	terragruntOptions *options.TerragruntOptions,
+	parsed *parsedFile,
+	filename string,
+	includeFromChild *config.IncludeConfig,
+	decodeList []config.PartialDecodeSectionType,
+) (*cty.Value, *config.TrackInclude, error) {
+	// Decode just the `include` and `import` blocks, and verify that it's allowed here
+	terragruntIncludeList, err := g.decodeAsTerragruntInclude(
+		parsed.file,
+		filename,
+		terragruntOptions,
+		config.EvalContextExtensions{PartialParseDecodeList: decodeList},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trackInclude := getTrackInclude(terragruntIncludeList, includeFromChild)
+
+	// Evaluate all the expressions in the locals block separately and generate the variables list to use in the
+	// evaluation context.
+	locals, err := g.evaluateLocalsBlock(terragruntOptions, parsed, filename, trackInclude, decodeList)
+	if err != nil {
+		return nil, trackInclude, err
+	}
+	localsAsCty, err := convertValuesMapToCtyVal(locals)
+	if err != nil {
+		return nil, trackInclude, err
+	}
+
+	return &localsAsCty, trackInclude, nil
+}
+
+// getTrackInclude converts the terragrunt include blocks into TrackInclude structs that differentiate between an
+// included config in the current parsing context, and an included config that was passed through from a previous
+// parsing context. Unlike Terragrunt, included configs can include others, so locals and blocks are inherited through
+// every level
+func getTrackInclude(terragruntIncludeList []config.IncludeConfig, includeFromChild *config.IncludeConfig) *config.TrackInclude {
+	terragruntIncludeMap := make(map[string]config.IncludeConfig, len(terragruntIncludeList))
+	for _, tgInc := range terragruntIncludeList {
+		terragruntIncludeMap[tgInc.Name] = tgInc
+	}
+
+	return &config.TrackInclude{
+		CurrentList: terragruntIncludeList,
+		CurrentMap:  terragruntIncludeMap,
+		Original:    includeFromChild,
+	}
+}
+
+// evaluateLocalsBlock is a routine to evaluate the locals block in a way to allow references to other locals. This
+// will:
+//   - Extract a reference to the locals block from the parsed file
+//   - Continuously evaluate the block until all references are evaluated, defering evaluation of anything that references
+//     other locals until those references are evaluated.
+//
+// This returns a map of the local names to the evaluated expressions (represented as `cty.Value` objects). This will
+// error if there are remaining unevaluated locals after all references that can be evaluated has been evaluated.
+func (g *Generator) evaluateLocalsBlock(
+	terragruntOptions *options.TerragruntOptions,
+	parsed *parsedFile,
+	filename string,
+	trackInclude *config.TrackInclude,
+	decodeList []config.PartialDecodeSectionType,
+) (map[string]cty.Value, error) {
+	diagsWriter := util.GetDiagnosticsWriter(terragruntOptions.Logger, parsed.parser)
+
+	localsBlock, diags := getLocalsBlock(parsed.file)
+	if diags.HasErrors() {
+		diagsWriter.WriteDiagnostics(diags)
+		return nil, errors.WithStackTrace(diags)
+	}
+	if localsBlock == nil {
+		// No locals block referenced in the file
+		return nil, nil
+	}
+
+	locals, diags := decodeLocalsBlock(localsBlock)
+	if diags.HasErrors() {
+		diagsWriter.WriteDiagnostics(diags)
+		return nil, errors.WithStackTrace(diags)
+	}
+
+	// Continuously attempt to evaluate the locals until there are no more locals to evaluate, or we can't evaluate
+	// further.
+	evaluatedLocals := map[string]cty.Value{}
+	evaluated := true
+	for iterations := 0; len(locals) > 0 && evaluated; iterations++ {
+		if iterations > config.MaxIter {
+			// Reached maximum supported iterations, which is most likely an infinite loop bug so cut the iteration
+			// short an return an error.
+			return nil, errors.WithStackTrace(config.MaxIterError{})
+		}
+
+		var err error
+		locals, evaluatedLocals, evaluated, err = g.attemptEvaluateLocals(
+			terragruntOptions,
+			filename,
+			locals,
+			evaluatedLocals,
+			trackInclude,
+			decodeList,
+			diagsWriter,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(locals) > 0 {
+		// This is an error because we couldn't evaluate all locals
+		for _, local := range locals {
+			_, reason := canEvaluate(local.Expr, evaluatedLocals)
+			terragruntOptions.Logger.Debugf("Could not evaluate local %s in %s [REASON: %s]", local.Name, filename, reason)
+		}
+		return nil, errors.WithStackTrace(config.CouldNotEvaluateAllLocalsError{})
+	}
+
+	return evaluatedLocals, nil
+}
+
+// attemptEvaluateLocals attempts to evaluate the locals block given the map of already evaluated locals, replacing
+// references to locals with the previously evaluated values. This will return:
+// - the list of remaining locals that were unevaluated in this attempt
+// - the updated map of evaluated locals after this attempt
+// - whether or not any locals were evaluated in this attempt
+// - any errors from the evaluation
+func (g *Generator) attemptEvaluateLocals(
+	terragruntOptions *options.TerragruntOptions,
+	filename string,
+	locals []*config.Local,
+	evaluatedLocals map[string]cty.Value,
+	trackInclude *config.TrackInclude,
+	decodeList []config.PartialDecodeSectionType,
+	diagsWriter hcl.DiagnosticWriter,
+) (unevaluatedLocals []*config.Local, newEvaluatedLocals map[string]cty.Value, evaluated bool, err error) {
+	// The HCL2 parser and especially cty conversions will panic in many types of errors, so we have to recover from
+	// those panics here and convert them to normal errors
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = errors.WithStackTrace(config.PanicWhileParsingConfig{RecoveredValue: recovered, ConfigFile: filename})
+		}
+	}()
+
+	evaluatedLocalsAsCty, err := convertValuesMapToCtyVal(evaluatedLocals)
+	if err != nil {
+		return nil, evaluatedLocals, false, err
+	}
+	evalCtx, err := g.createEvalContext(
+		filename,
+		terragruntOptions,
+		config.EvalContextExtensions{
+			TrackInclude:           trackInclude,
+			Locals:                 &evaluatedLocalsAsCty,
+			PartialParseDecodeList: decodeList,
+		},
+	)
+	if err != nil {
+		return nil, evaluatedLocals, false, err
+	}
+
+	unevaluatedLocals = []*config.Local{}
+	evaluated = false
+	newEvaluatedLocals = map[string]cty.Value{}
+	for key, val := range evaluatedLocals {
+		newEvaluatedLocals[key] = val
+	}
+	for _, local := range locals {
+		localEvaluated, _ := canEvaluate(local.Expr, evaluatedLocals)
+		if localEvaluated {
+			evaluatedVal, diags := local.Expr.Value(evalCtx)
+			if diags.HasErrors() {
+				diagsWriter.WriteDiagnostics(diags)
+				return nil, evaluatedLocals, false, errors.WithStackTrace(diags)
+			}
+			newEvaluatedLocals[local.Name] = evaluatedVal
+			evaluated = true
+		} else {
+			unevaluatedLocals = append(unevaluatedLocals, local)
+		}
+	}
+
+	return unevaluatedLocals, newEvaluatedLocals, evaluated, nil
+}
+
+// canEvaluate determines if the local expression can be evaluated. An expression can be evaluated if one of the
+// following is true:
+// - It has no references to other locals.
+// - It has references to other locals that have already been evaluated.
+// Note that the second return value is a human friendly reason for why the expression can not be evaluated, and is
+// useful for error reporting.
+func canEvaluate(expression hcl.Expression, evaluatedLocals map[string]cty.Value) (bool, string) {
+	for _, traversal := range expression.Variables() {
+		rootName := traversal.RootName()
+
+		// If the variable is `include`, then we can evaluate it now
+		if rootName == "include" {
+			continue
+		}
+
+		// We can't evaluate any variable other than `local`
+		if rootName != "local" {
+			return false, fmt.Sprintf("%s is not defined, only other locals can be referenced", rootName)
+		}
+
+		// If we can't get any local name, we can't evaluate it.
+		localName := getLocalName(traversal)
+		if localName == "" {
+			return false, "local var name can not be determined"
+		}
+
+		// If the referenced local isn't evaluated, we can't evaluate this expression.
+		if _, hasEvaluated := evaluatedLocals[localName]; !hasEvaluated {
+			return false, fmt.Sprintf("local reference '%s' is not evaluated", localName)
+		}
+	}
+
+	// If we made it this far, this means all the variables referenced are accounted for and we can evaluate this
+	// expression.
+	return true, ""
+}
+
+// getLocalName takes a variable reference encoded as a HCL tree traversal that is rooted at the name `local` and
+// returns the underlying variable lookup on the local map. If it is not a local name lookup, this will return empty
+// string.
+func getLocalName(traversal hcl.Traversal) string {
+	if traversal.IsRelative() || traversal.RootName() != "local" {
+		return ""
+	}
+
+	for _, relRaw := range traversal.SimpleSplit().Rel {
+		if rel, ok := relRaw.(hcl.TraverseAttr); ok {
+			return rel.Name
+		}
+	}
+	return ""
+}
+
+// getLocalsBlock takes a parsed HCL file and extracts a reference to the `locals` block, if there is one defined.
+func getLocalsBlock(hclFile *hcl.File) (*hcl.Block, hcl.Diagnostics) {
+	localsSchema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "locals"}},
+	}
+	// We use PartialContent here, because we are only interested in parsing out the locals block.
+	parsedLocals, _, diags := hclFile.Body.PartialContent(localsSchema)
+	switch len(parsedLocals.Blocks) {
+	case 0:
+		return nil, diags
+	case 1:
+		return parsedLocals.Blocks[0], diags
+	default:
+		// We currently only support parsing a single locals block
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Multiple locals block",
+			Detail:   "Terragrunt currently does not support multiple locals blocks in a single config. Consolidate to a single locals block.",
+		})
+	}
+}
+
+// decodeLocalsBlock loads the block into name expression pairs to assist with evaluation of the locals prior to
+// evaluating the whole config.
+func decodeLocalsBlock(localsBlock *hcl.Block) ([]*config.Local, hcl.Diagnostics) {
+	attrs, diags := localsBlock.Body.JustAttributes()
+	if len(attrs) == 0 {
+		return nil, diags
+	}
+
+	locals := make([]*config.Local, 0, len(attrs))
+	for name, attr := range attrs {
+		if !hclsyntax.ValidIdentifier(name) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid local value name",
+				Detail:   "A name must start with a letter and may contain only letters, digits, underscores, and dashes.",
+				Subject:  &attr.NameRange,
+			})
+		}
+
+		locals = append(locals, &config.Local{Name: name, Expr: attr.Expr})
+	}
+	return locals, diags
+}
+
+// convertValuesMapToCtyVal takes a map of name - cty.Value pairs and converts to a single cty.Value object.
+func convertValuesMapToCtyVal(valMap map[string]cty.Value) (cty.Value, error) {
+	if len(valMap) == 0 {
+		return cty.NilVal, nil
+	}
+
+	// An object type is the only map type that allows a different type for each attribute
+	outType := map[string]cty.Type{}
+	for k, v := range valMap {
+		outType[k] = v.Type()
+	}
+	valMapAsCty, err := gocty.ToCtyValue(valMap, cty.Object(outType))
+	if err != nil {
+		return cty.NilVal, errors.WithStackTrace(err)
+	}
+	return valMapAsCty, nil
+}
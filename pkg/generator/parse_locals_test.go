@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestMergeLocalValue(t *testing.T) {
+	list := func(values ...string) cty.Value {
+		elements := []cty.Value{}
+		for _, value := range values {
+			elements = append(elements, cty.StringVal(value))
+		}
+		return cty.TupleVal(elements)
+	}
+
+	tests := []struct {
+		name      string
+		parent    cty.Value
+		child     cty.Value
+		behaviour string
+		want      cty.Value
+	}{
+		{name: "append lists", parent: list("*.md"), child: list("docs/"), behaviour: MergeAppend, want: list("*.md", "docs/")},
+		{name: "replace lists", parent: list("*.md"), child: list("docs/"), behaviour: MergeReplace, want: list("docs/")},
+		{name: "append replaces strings", parent: cty.StringVal("development"), child: cty.StringVal("production"), behaviour: MergeAppend, want: cty.StringVal("production")},
+		{name: "different types", parent: list("*.md"), child: cty.StringVal("docs/"), behaviour: MergeAppend, want: cty.StringVal("docs/")},
+		{name: "null child", parent: list("*.md"), child: cty.NullVal(cty.List(cty.String)), behaviour: MergeAppend, want: cty.NullVal(cty.List(cty.String))},
+		{
+			name:      "append merges maps key by key",
+			parent:    cty.ObjectVal(map[string]cty.Value{"changes": list("../dns"), "cascade": list("../vpc")}),
+			child:     cty.ObjectVal(map[string]cty.Value{"changes": list("../iam")}),
+			behaviour: MergeAppend,
+			want:      cty.ObjectVal(map[string]cty.Value{"changes": list("../iam"), "cascade": list("../vpc")}),
+		},
+		{
+			name:      "replace maps",
+			parent:    cty.ObjectVal(map[string]cty.Value{"changes": list("../dns"), "cascade": list("../vpc")}),
+			child:     cty.ObjectVal(map[string]cty.Value{"changes": list("../iam")}),
+			behaviour: MergeReplace,
+			want:      cty.ObjectVal(map[string]cty.Value{"changes": list("../iam")}),
+		},
+		{
+			name:      "deep-merge merges nested values",
+			parent:    cty.ObjectVal(map[string]cty.Value{"changes": list("../dns"), "cascade": list("../vpc")}),
+			child:     cty.ObjectVal(map[string]cty.Value{"changes": list("../iam")}),
+			behaviour: MergeDeepMerge,
+			want:      cty.ObjectVal(map[string]cty.Value{"changes": list("../dns", "../iam"), "cascade": list("../vpc")}),
+		},
+		{
+			name: "deep-merge merges nested maps",
+			parent: cty.ObjectVal(map[string]cty.Value{
+				"tenant": cty.ObjectVal(map[string]cty.Value{"TENANT": cty.StringVal("acme"), "TIER": cty.StringVal("gold")}),
+			}),
+			child: cty.ObjectVal(map[string]cty.Value{
+				"tenant": cty.ObjectVal(map[string]cty.Value{"TIER": cty.StringVal("silver")}),
+			}),
+			behaviour: MergeDeepMerge,
+			want: cty.ObjectVal(map[string]cty.Value{
+				"tenant": cty.ObjectVal(map[string]cty.Value{"TENANT": cty.StringVal("acme"), "TIER": cty.StringVal("silver")}),
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeLocalValue(tt.parent, tt.child, tt.behaviour); !got.RawEquals(tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Each module of test/projects/include_chain includes an env.hcl, which includes root.hcl
+func TestIncludeChainLocals(t *testing.T) {
+	tests := []struct {
+		module        string
+		environment   string
+		skip          bool
+		ignoreChanges []string
+	}{
+		// Its own gitlab_ci_ignore_changes replaces the inherited one
+		{module: "prod/app", environment: "production", ignoreChanges: []string{"docs/"}},
+		// Its own gitlab_ci_ignore_changes is appended to the inherited one
+		{module: "prod/db", environment: "production", ignoreChanges: []string{"*.md", "*.tfvars"}},
+		// Second level settings, on top of the first level ones
+		{module: "staging/app", environment: "development", skip: true, ignoreChanges: []string{"*.md"}},
+	}
+
+	g := newTestGenerator(t, "projects/include_chain", nil)
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			path := filepath.Join(g.root, filepath.FromSlash(tt.module), "terragrunt.hcl")
+			terragruntOptions, err := g.newTerragruntOptions(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			locals, err := g.parseLocals(path, terragruntOptions, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if locals.Environment == nil || *locals.Environment != tt.environment {
+				t.Errorf("environment: got %v, want %s", locals.Environment, tt.environment)
+			}
+			if skip := locals.Skip != nil && *locals.Skip; skip != tt.skip {
+				t.Errorf("skip: got %v, want %v", skip, tt.skip)
+			}
+			if !reflect.DeepEqual(locals.IgnoreChanges, tt.ignoreChanges) {
+				t.Errorf("ignore changes: got %v, want %v", locals.IgnoreChanges, tt.ignoreChanges)
+			}
+			// From the first level, through the second
+			want := []string{filepath.ToSlash(filepath.Join(g.root, "common.yaml"))}
+			if !reflect.DeepEqual(locals.ExtraGitlabCiDependencies, want) {
+				t.Errorf("extra dependencies: got %v, want %v", locals.ExtraGitlabCiDependencies, want)
+			}
+			if len(locals.uninheritedParents) != 0 {
+				t.Errorf("uninherited parents: got %v, want none", locals.uninheritedParents)
+			}
+		})
+	}
+}
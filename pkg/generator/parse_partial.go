@@ -0,0 +1,365 @@
+package generator
+
+// Terragrunt's partial parsing evaluates every expression with its own set of functions. To be able to control how
+// configs are evaluated (see sandbox.go), this file mostly follows along how `config.PartialParseConfigString` decodes
+// the blocks this tool cares about, and how it merges them with the included configs.
+//
+// It is used with the sandbox off too, as Terragrunt reads and parses a file again for every partial parse, and for
+// every config including it, while this file takes them from the store (see parse_cache.go). It also lets included
+// configs include others. Otherwise, it must decode the same blocks as Terragrunt does, which
+// parse_partial_test.go checks.
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/gruntwork-io/go-commons/errors"
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// terragruntDependencies is a struct that can be used to only decode the dependencies block.
+type terragruntDependencies struct {
+	Dependencies *config.ModuleDependencies `hcl:"dependencies,block"`
+	Remain       hcl.Body                   `hcl:",remain"`
+}
+
+// terragruntTerraform is a struct that can be used to only decode the terraform block
+type terragruntTerraform struct {
+	Terraform *config.TerraformConfig `hcl:"terraform,block"`
+	Remain    hcl.Body                `hcl:",remain"`
+}
+
+// terragruntTerraformSource is a struct that can be used to only decode the terraform block, and only the source
+// attribute.
+type terragruntTerraformSource struct {
+	Terraform *terraformConfigSourceOnly `hcl:"terraform,block"`
+	Remain    hcl.Body                   `hcl:",remain"`
+}
+
+// terraformConfigSourceOnly is a struct that can be used to decode only the source attribute of the terraform block.
+type terraformConfigSourceOnly struct {
+	Source *string  `hcl:"source,attr"`
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// terragruntDependency is a struct that can be used to only decode the dependency blocks in the terragrunt config
+type terragruntDependency struct {
+	Dependencies []config.Dependency `hcl:"dependency,block"`
+	Remain       hcl.Body            `hcl:",remain"`
+}
+
+// terragruntRemoteState is a struct that can be used to only decode the remote_state block
+type terragruntRemoteState struct {
+	RemoteState *remoteStateConfigFile `hcl:"remote_state,block"`
+	Remain      hcl.Body               `hcl:",remain"`
+}
+
+// remoteStateConfigFile is a struct that can be used to decode the backend and config of the remote_state block.
+// Settings that only matter to Terragrunt itself, like `generate`, are left out.
+type remoteStateConfigFile struct {
+	Backend string    `hcl:"backend,attr"`
+	Config  cty.Value `hcl:"config,attr"`
+	Remain  hcl.Body  `hcl:",remain"`
+}
+
+// partialParseConfig partially parses and decodes the file at `filename`. Which blocks/attributes to decode is
+// controlled by the function parameter decodeList. Valid values are:
+//   - DependenciesBlock: Parses the `dependencies` block in the config
+//   - DependencyBlock: Parses the `dependency` block in the config
+//   - TerraformBlock: Parses the `terraform` block in the config
+//   - TerraformSource: Parses only the `source` attribute of the `terraform` block in the config
+//   - RemoteStateBlock: Parses the `backend` and `config` of the `remote_state` block in the config
+//
+// Note that the `locals` and `include` blocks are always decoded.
+func (g *Generator) partialParseConfig(
+	filename string,
+	terragruntOptions *options.TerragruntOptions,
+	includeFromChild *config.IncludeConfig,
+	decodeList []config.PartialDecodeSectionType,
+) (*config.TerragruntConfig, error) {
+	parsed, err := g.store.file(filename)
+	if err != nil {
+		return nil, err
+	}
+	file := parsed.file
+
+	// Decode just the Base blocks. See the function docs for decodeBaseBlocks for more info on what base blocks are.
+	localsAsCty, trackInclude, err := g.decodeBaseBlocks(terragruntOptions, parsed, filename, includeFromChild, decodeList)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize evaluation context extensions from base blocks.
+	contextExtensions := config.EvalContextExtensions{
+		Locals:                 localsAsCty,
+		TrackInclude:           trackInclude,
+		PartialParseDecodeList: decodeList,
+	}
+
+	output := config.TerragruntConfig{IsPartial: true}
+
+	// Set parsed Locals on the parsed config
+	if localsAsCty != nil && *localsAsCty != cty.NilVal {
+		localsParsed, err := parseCtyValueToMap(*localsAsCty)
+		if err != nil {
+			return nil, err
+		}
+		output.Locals = localsParsed
+	}
+
+	// Now loop through each requested block / component to decode from the terragrunt config, decode them, and merge
+	// them into the output TerragruntConfig struct.
+	for _, decode := range decodeList {
+		switch decode {
+		case config.DependenciesBlock:
+			decoded := terragruntDependencies{}
+			if err := g.decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions); err != nil {
+				return nil, err
+			}
+
+			// If we already decoded some dependencies, merge them in. Otherwise, set as the new list.
+			if output.Dependencies != nil {
+				output.Dependencies.Merge(decoded.Dependencies)
+			} else {
+				output.Dependencies = decoded.Dependencies
+			}
+
+		case config.TerraformBlock:
+			decoded := terragruntTerraform{}
+			if err := g.decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions); err != nil {
+				return nil, err
+			}
+			output.Terraform = decoded.Terraform
+
+		case config.TerraformSource:
+			decoded := terragruntTerraformSource{}
+			if err := g.decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions); err != nil {
+				return nil, err
+			}
+			if decoded.Terraform != nil {
+				output.Terraform = &config.TerraformConfig{Source: decoded.Terraform.Source}
+			}
+
+		case config.DependencyBlock:
+			decoded := terragruntDependency{}
+			if err := g.decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions); err != nil {
+				return nil, err
+			}
+			output.TerragruntDependencies = decoded.Dependencies
+
+			// Convert dependency blocks into module depenency lists. If we already decoded some dependencies,
+			// merge them in. Otherwise, set as the new list.
+			dependencies := dependencyBlocksToModuleDependencies(decoded.Dependencies)
+			if output.Dependencies != nil {
+				output.Dependencies.Merge(dependencies)
+			} else {
+				output.Dependencies = dependencies
+			}
+
+		case config.RemoteStateBlock:
+			decoded := terragruntRemoteState{}
+			if err := g.decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions); err != nil {
+				return nil, err
+			}
+			if decoded.RemoteState != nil {
+				remoteStateConfig, err := parseCtyValueToMap(decoded.RemoteState.Config)
+				if err != nil {
+					return nil, err
+				}
+				output.RemoteState = &remote.RemoteState{Backend: decoded.RemoteState.Backend, Config: remoteStateConfig}
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported partial block code %d", decode)
+		}
+	}
+
+	// If this file includes another, parse and merge the partial blocks. Otherwise just return this config.
+	if len(trackInclude.CurrentList) > 0 {
+		merged, err := g.handleIncludePartial(filename, &output, trackInclude, terragruntOptions, decodeList)
+		if err != nil {
+			return nil, err
+		}
+		merged.ProcessedIncludes = trackInclude.CurrentMap
+		return merged, nil
+	}
+	return &output, nil
+}
+
+// partialParseConfigFile is a memoized version of `partialParseConfig`
+func (g *Generator) partialParseConfigFile(
+	filename string,
+	terragruntOptions *options.TerragruntOptions,
+	includeFromChild *config.IncludeConfig,
+	decodeList []config.PartialDecodeSectionType,
+) (*config.TerragruntConfig, error) {
+	return g.store.partialParseConfigFile(filename, terragruntOptions, includeFromChild, decodeList, func() (*config.TerragruntConfig, error) {
+		return g.partialParseConfig(filename, terragruntOptions, includeFromChild, decodeList)
+	})
+}
+
+// handleIncludePartial merges the partially parsed include configs into the child config according to the strategy
+// specified by the user.
+func (g *Generator) handleIncludePartial(
+	filename string,
+	baseConfig *config.TerragruntConfig,
+	trackInclude *config.TrackInclude,
+	terragruntOptions *options.TerragruntOptions,
+	decodeList []config.PartialDecodeSectionType,
+) (*config.TerragruntConfig, error) {
+	// We merge in the include blocks in reverse order here. The expectation is that the bottom most elements override
+	// those in earlier includes, so we need to merge bottom up instead of top down to ensure this.
+	includeList := trackInclude.CurrentList
+	for i := len(includeList) - 1; i >= 0; i-- {
+		includeConfig := includeList[i]
+		mergeStrategy, err := includeConfig.GetMergeStrategy()
+		if err != nil {
+			return nil, err
+		}
+
+		parsedIncludeConfig, err := g.partialParseIncludedConfig(filename, &includeConfig, terragruntOptions, decodeList)
+		if err != nil {
+			return nil, err
+		}
+
+		switch mergeStrategy {
+		case config.NoMerge:
+			continue
+		case config.ShallowMerge:
+			parsedIncludeConfig.Merge(baseConfig, terragruntOptions)
+		case config.DeepMerge:
+			if err := parsedIncludeConfig.DeepMerge(baseConfig, terragruntOptions); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported merge strategy %s for include %s", mergeStrategy, includeConfig.Path)
+		}
+		baseConfig = parsedIncludeConfig
+	}
+	return baseConfig, nil
+}
+
+// partialParseIncludedConfig partially parses the config included by `filename`. The result is a copy, so it can be
+// merged into without changing what other children of the same parent see.
+func (g *Generator) partialParseIncludedConfig(
+	filename string,
+	includedConfig *config.IncludeConfig,
+	terragruntOptions *options.TerragruntOptions,
+	decodeList []config.PartialDecodeSectionType,
+) (*config.TerragruntConfig, error) {
+	if includedConfig.Path == "" {
+		return nil, errors.WithStackTrace(config.IncludedConfigMissingPath(filename))
+	}
+
+	includePath := includedConfig.Path
+	if !filepath.IsAbs(includePath) {
+		includePath = util.JoinPath(filepath.Dir(filename), includePath)
+	}
+
+	parsedConfig, err := g.partialParseConfigFile(includePath, terragruntOptions, includedConfig, decodeList)
+	if err != nil {
+		return nil, err
+	}
+	copied := *parsedConfig
+	if parsedConfig.Terraform != nil {
+		terraform := *parsedConfig.Terraform
+		terraform.ExtraArgs = append([]config.TerraformExtraArguments{}, terraform.ExtraArgs...)
+		terraform.BeforeHooks = append([]config.Hook{}, terraform.BeforeHooks...)
+		terraform.AfterHooks = append([]config.Hook{}, terraform.AfterHooks...)
+		terraform.ErrorHooks = append([]config.ErrorHook{}, terraform.ErrorHooks...)
+		copied.Terraform = &terraform
+	}
+	if parsedConfig.Dependencies != nil {
+		copied.Dependencies = &config.ModuleDependencies{Paths: append([]string{}, parsedConfig.Dependencies.Paths...)}
+	}
+	copied.TerragruntDependencies = append([]config.Dependency{}, parsedConfig.TerragruntDependencies...)
+	return &copied, nil
+}
+
+// includeMapAsCtyVal converts the include map into a cty.Value struct that can be exposed to the child config. For
+// backward compatibility, this function will return the included config object if the config only defines a single
+// bare include block that is exposed.
+func (g *Generator) includeMapAsCtyVal(
+	filename string,
+	includeMap map[string]config.IncludeConfig,
+	terragruntOptions *options.TerragruntOptions,
+	decodeList []config.PartialDecodeSectionType,
+) (cty.Value, error) {
+	bareInclude, hasBareInclude := includeMap[bareIncludeKey]
+	if len(includeMap) == 1 && hasBareInclude {
+		return g.includeConfigAsCtyVal(filename, bareInclude, terragruntOptions, decodeList)
+	}
+
+	exposedIncludeMap := map[string]cty.Value{}
+	for key, included := range includeMap {
+		parsedIncludedCty, err := g.includeConfigAsCtyVal(filename, included, terragruntOptions, decodeList)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if parsedIncludedCty != cty.NilVal {
+			exposedIncludeMap[key] = parsedIncludedCty
+		}
+	}
+	return convertValuesMapToCtyVal(exposedIncludeMap)
+}
+
+// includeConfigAsCtyVal returns the parsed include block as a cty.Value object if expose is true. Otherwise, return
+// the nil representation of cty.Value.
+func (g *Generator) includeConfigAsCtyVal(
+	filename string,
+	includeConfig config.IncludeConfig,
+	terragruntOptions *options.TerragruntOptions,
+	decodeList []config.PartialDecodeSectionType,
+) (cty.Value, error) {
+	if !includeConfig.GetExpose() {
+		return cty.NilVal, nil
+	}
+
+	parsedIncluded, err := g.partialParseIncludedConfig(filename, &includeConfig, terragruntOptions, decodeList)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return config.TerragruntConfigAsCty(parsedIncluded)
+}
+
+// Convert the list of parsed Dependency blocks into a list of module dependencies. Each output block should
+// become a dependency of the current config, since that module has to be applied before we can read the output.
+func dependencyBlocksToModuleDependencies(decodedDependencyBlocks []config.Dependency) *config.ModuleDependencies {
+	if len(decodedDependencyBlocks) == 0 {
+		return nil
+	}
+
+	paths := []string{}
+	for _, decodedDependencyBlock := range decodedDependencyBlocks {
+		configPath := decodedDependencyBlock.ConfigPath
+		if util.IsFile(configPath) && filepath.Base(configPath) == config.DefaultTerragruntConfigPath {
+			// dependencies system expects the directory containing the terragrunt.hcl file
+			configPath = filepath.Dir(configPath)
+		}
+		paths = append(paths, configPath)
+	}
+	return &config.ModuleDependencies{Paths: paths}
+}
+
+// parseCtyValueToMap converts a cty Value to a Go map[string]interface{} by going through JSON, as cty does not
+// support this directly.
+func parseCtyValueToMap(value cty.Value) (map[string]interface{}, error) {
+	jsonBytes, err := ctyjson.Marshal(value, cty.DynamicPseudoType)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	var ctyJsonOutput config.CtyJsonOutput
+	if err := json.Unmarshal(jsonBytes, &ctyJsonOutput); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	return ctyJsonOutput.Value, nil
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+)
+
+// With the sandbox off, partial parses must decode the same blocks as Terragrunt does. The only intended differences
+// are that files come from the store, that the remote_state block only has its backend and config, and that included
+// configs can include others, which Terragrunt refuses
+func TestPartialParseMatchesTerragrunt(t *testing.T) {
+	decodeList := []config.PartialDecodeSectionType{
+		config.DependenciesBlock,
+		config.DependencyBlock,
+		config.TerraformSource,
+		config.RemoteStateBlock,
+	}
+
+	for _, root := range []string{"projects/include_chain", "projects/chained_dependencies", "projects/remote_state", "projects/edge_kinds",
+		"projects/multi_accounts_vpc_route53_tgw", "projects/terragrunt-infrastructure-live-example"} {
+		t.Run(root, func(t *testing.T) {
+			g := newTestGenerator(t, root, func(opts *Options) {
+				opts.Sandbox = false
+			})
+			err := filepath.WalkDir(g.root, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.Name() != config.DefaultTerragruntConfigPath {
+					return err
+				}
+
+				terragruntOptions, err := g.newTerragruntOptions(path)
+				if err != nil {
+					return err
+				}
+				// Parents are only evaluated from the modules including them
+				if role, _, err := g.parseModule(path, terragruntOptions); err != nil || role != roleModule {
+					return err
+				}
+				got, err := g.partialParseConfigFile(path, terragruntOptions, nil, decodeList)
+				if err != nil {
+					return err
+				}
+				name, _ := filepath.Rel(g.root, path)
+				want, err := config.PartialParseConfigFile(path, terragruntOptions, nil, decodeList)
+				var tooManyLevels config.TooManyLevelsOfInheritance
+				if errors.As(err, &tooManyLevels) {
+					t.Logf("%s has several levels of includes, which only this tool parses", name)
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if !reflect.DeepEqual(got.Dependencies, want.Dependencies) {
+					t.Errorf("%s dependencies: got %v, want %v", name, got.Dependencies, want.Dependencies)
+				}
+				if !reflect.DeepEqual(dependencyPaths(got), dependencyPaths(want)) {
+					t.Errorf("%s dependency blocks: got %v, want %v", name, dependencyPaths(got), dependencyPaths(want))
+				}
+				if !reflect.DeepEqual(terraformSource(got), terraformSource(want)) {
+					t.Errorf("%s terraform source: got %q, want %q", name, terraformSource(got), terraformSource(want))
+				}
+				if (got.RemoteState == nil) != (want.RemoteState == nil) ||
+					got.RemoteState != nil && (got.RemoteState.Backend != want.RemoteState.Backend || !reflect.DeepEqual(got.RemoteState.Config, want.RemoteState.Config)) {
+					t.Errorf("%s remote state: got %+v, want %+v", name, got.RemoteState, want.RemoteState)
+				}
+				if !reflect.DeepEqual(got.Locals, want.Locals) {
+					t.Errorf("%s locals: got %v, want %v", name, got.Locals, want.Locals)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func dependencyPaths(parsed *config.TerragruntConfig) []string {
+	paths := []string{}
+	for _, dependency := range parsed.TerragruntDependencies {
+		paths = append(paths, dependency.ConfigPath)
+	}
+	return paths
+}
+
+func terraformSource(parsed *config.TerragruntConfig) string {
+	if parsed.Terraform == nil || parsed.Terraform.Source == nil {
+		return ""
+	}
+	return *parsed.Terraform.Source
+}
@@ -0,0 +1,231 @@
+package generator
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+var localModuleSourcePrefixes = []string{
+	"./",
+	"../",
+	".\\",
+	"..\\",
+}
+
+// pathModulePrefix is the interpolation some configs start local module sources with
+const pathModulePrefix = "${path.module}"
+
+// ModuleCall is a call to a local Terraform module, along with the calls that module makes in turn
+type ModuleCall struct {
+	// Name of the `module` block
+	Name string
+	// Source as set in the `module` block
+	Source string
+	// Directory of the called module, relative to the root when it is within it
+	Dir string
+	// Calls made by the called module
+	Calls []ModuleCall
+	// True when the called module is already higher in the tree. Its calls are then not repeated
+	Cycle bool
+
+	pos tfconfig.SourcePos
+	// True when a cycle was cut below the call
+	cyclic bool
+}
+
+// localModuleCall is a call to a local module, as found in the Terraform code of a directory
+type localModuleCall struct {
+	name   string
+	source string
+	// Absolute directory of the called module
+	dir string
+	pos tfconfig.SourcePos
+}
+
+// moduleCallNode is a directory of the module-call graph
+type moduleCallNode struct {
+	calls []localModuleCall
+	err   error
+}
+
+// localModuleCalls returns the calls to local modules made by the Terraform code in `dir`. Every directory is only
+// loaded once per run, however many modules share it
+func (g *Generator) localModuleCalls(dir string) ([]localModuleCall, error) {
+	g.moduleCallsMtx.RLock()
+	node, ok := g.moduleCallNodes[dir]
+	g.moduleCallsMtx.RUnlock()
+	if ok {
+		return node.calls, node.err
+	}
+
+	res, _, _ := g.terraformGroup.Do("calls:"+dir, func() (interface{}, error) {
+		g.moduleCallsMtx.RLock()
+		node, ok := g.moduleCallNodes[dir]
+		g.moduleCallsMtx.RUnlock()
+		if ok {
+			return node, nil
+		}
+
+		node = loadModuleCallNode(dir)
+		g.moduleCallsMtx.Lock()
+		g.moduleCallNodes[dir] = node
+		g.moduleCallsMtx.Unlock()
+		return node, nil
+	})
+	node = res.(*moduleCallNode)
+	return node.calls, node.err
+}
+
+// loadModuleCallNode loads the calls to local modules made by the Terraform code in `dir`
+func loadModuleCallNode(dir string) *moduleCallNode {
+	node := &moduleCallNode{calls: []localModuleCall{}}
+	module, diags := tfconfig.LoadModule(dir)
+	if diags.HasErrors() {
+		node.err = errors.New(diags.Error())
+	} else {
+		for _, mc := range module.ModuleCalls {
+			calledDir, ok := localModuleDir(dir, mc.Source)
+			if !ok {
+				continue
+			}
+			node.calls = append(node.calls, localModuleCall{name: mc.Name, source: mc.Source, dir: calledDir, pos: mc.Pos})
+		}
+		sort.Slice(node.calls, func(i, j int) bool {
+			return node.calls[i].name < node.calls[j].name
+		})
+	}
+	return node
+}
+
+// localModuleDir returns the absolute directory of the module called with `source` from the Terraform code in `dir`,
+// when it is a local one
+func localModuleDir(dir string, source string) (string, bool) {
+	if strings.HasPrefix(source, pathModulePrefix) {
+		source = "." + strings.TrimPrefix(source, pathModulePrefix)
+	}
+	if filepath.IsAbs(source) {
+		return filepath.Clean(source), true
+	}
+	if isLocalTerraformModuleSource(source) {
+		return filepath.Join(dir, source), true
+	}
+	return "", false
+}
+
+// parseTerraformLocalModuleSource returns the `*.tf*` globs of every local module called, directly or not, by the
+// Terraform code in `path`
+func (g *Generator) parseTerraformLocalModuleSource(path string) ([]string, error) {
+	path = filepath.Clean(path)
+	visited := map[string]bool{path: true}
+	queue := []string{path}
+	sources := []string{}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		calls, err := g.localModuleCalls(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range calls {
+			if visited[call.dir] {
+				continue
+			}
+			visited[call.dir] = true
+			queue = append(queue, call.dir)
+			sources = append(sources, util.JoinPath(call.dir, "*.tf*"))
+		}
+	}
+
+	sort.Strings(sources)
+	return sources, nil
+}
+
+// moduleCallTree returns the tree of local modules called by the Terraform code in `dir`. A module calling one of its
+// callers ends its branch, marked as a cycle
+func (g *Generator) moduleCallTree(dir string) ([]ModuleCall, error) {
+	tree, _, err := g.moduleCallSubtree(filepath.Clean(dir), map[string]bool{})
+	return tree, err
+}
+
+// moduleCallSubtree returns the tree of local modules called from `dir`, below the `callers` of `dir`, and whether a
+// cycle was cut in it. Trees without cycles do not depend on the callers, so they are only built once per directory,
+// which keeps modules called through many paths from being expanded again for each of them
+func (g *Generator) moduleCallSubtree(dir string, callers map[string]bool) ([]ModuleCall, bool, error) {
+	g.moduleCallsMtx.RLock()
+	tree, ok := g.moduleCallSubtrees[dir]
+	g.moduleCallsMtx.RUnlock()
+	if ok {
+		return tree, false, nil
+	}
+
+	calls, err := g.localModuleCalls(dir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	callers[dir] = true
+	defer delete(callers, dir)
+
+	cyclic := false
+	tree = []ModuleCall{}
+	for _, call := range calls {
+		moduleCall := ModuleCall{
+			Name:   call.name,
+			Source: call.source,
+			Dir:    g.displayPath(call.dir),
+			Calls:  []ModuleCall{},
+			pos:    call.pos,
+		}
+		if callers[call.dir] {
+			log.Debug("Module ", call.name, " in ", dir, " calls ", call.dir, ", which is already calling it")
+			moduleCall.Cycle = true
+			cyclic = true
+		} else {
+			moduleCall.Calls, moduleCall.cyclic, err = g.moduleCallSubtree(call.dir, callers)
+			if err != nil {
+				return nil, false, err
+			}
+			cyclic = cyclic || moduleCall.cyclic
+		}
+		tree = append(tree, moduleCall)
+	}
+
+	if !cyclic {
+		g.moduleCallsMtx.Lock()
+		g.moduleCallSubtrees[dir] = tree
+		g.moduleCallsMtx.Unlock()
+	}
+	return tree, cyclic, nil
+}
+
+// moduleCallCycles returns the calls of `tree` that close a cycle. Subtrees without cycles are skipped, as they can be
+// shared by many calls
+func moduleCallCycles(tree []ModuleCall) []ModuleCall {
+	cycles := []ModuleCall{}
+	for _, call := range tree {
+		if call.Cycle {
+			cycles = append(cycles, call)
+		}
+		if call.cyclic {
+			cycles = append(cycles, moduleCallCycles(call.Calls)...)
+		}
+	}
+	return cycles
+}
+
+func isLocalTerraformModuleSource(raw string) bool {
+	for _, prefix := range localModuleSourcePrefixes {
+		if strings.HasPrefix(raw, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
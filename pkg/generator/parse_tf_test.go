@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A module called through many paths must only be expanded once: each of these levels calls the next one twice, which
+// would expand 2^levels subtrees otherwise
+func TestModuleCallTreeDiamonds(t *testing.T) {
+	const levels = 40
+	root := t.TempDir()
+	for level := 0; level < levels; level++ {
+		dir := filepath.Join(root, fmt.Sprintf("level%d", level))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		code := "variable \"name\" {}\n"
+		if level < levels-1 {
+			next := fmt.Sprintf("../level%d", level+1)
+			code += fmt.Sprintf("module \"left\" {\n  source = %q\n}\n\nmodule \"right\" {\n  source = %q\n}\n", next, next)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.Root = root
+	g, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := g.moduleCallTree(filepath.Join(root, "level0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	depth := 0
+	for calls := tree; len(calls) > 0; calls = calls[0].Calls {
+		if len(calls) != 2 || calls[0].Name != "left" || calls[1].Name != "right" {
+			t.Fatalf("level %d: got calls %+v, want left and right", depth, calls)
+		}
+		if calls[0].Cycle || calls[1].Cycle {
+			t.Fatalf("level %d: got a cycle", depth)
+		}
+		depth++
+	}
+	if depth != levels-1 {
+		t.Errorf("got %d levels of calls, want %d", depth, levels-1)
+	}
+	if len(moduleCallCycles(tree)) != 0 {
+		t.Errorf("got cycles %+v, want none", moduleCallCycles(tree))
+	}
+}
+
+// Trees with cycles depend on the callers, so the cycle is cut wherever the tree is entered from
+func TestModuleCallTreeCycles(t *testing.T) {
+	root := t.TempDir()
+	for name, next := range map[string]string{"a": "../b", "b": "../a"} {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		code := fmt.Sprintf("module \"next\" {\n  source = %q\n}\n", next)
+		if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.Root = root
+	g, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, start := range []string{"a", "b", "a"} {
+		tree, err := g.moduleCallTree(filepath.Join(root, start))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cycles := moduleCallCycles(tree)
+		if len(cycles) != 1 || cycles[0].Dir != start {
+			t.Errorf("from %s: got cycles %+v, want a call back to %s", start, cycles, start)
+		}
+	}
+}
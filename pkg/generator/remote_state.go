@@ -0,0 +1,238 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	log "github.com/sirupsen/logrus"
+)
+
+// What to do when several modules store their state at the same location
+const (
+	DuplicateStateKeysWarn  = "warn"
+	DuplicateStateKeysError = "error"
+)
+
+// stateKeyAttributes is the `remote_state.config` attribute holding the key of the state within the backend, for the
+// backends that have one
+var stateKeyAttributes = map[string]string{
+	"s3":         "key",
+	"gcs":        "prefix",
+	"azurerm":    "key",
+	"oss":        "key",
+	"cos":        "key",
+	"local":      "path",
+	"consul":     "path",
+	"http":       "address",
+	"kubernetes": "secret_suffix",
+	"pg":         "schema_name",
+}
+
+// stateLocationAttributes are the `remote_state.config` attributes that, together, locate a state, for the backends
+// that are known. The whole config is used for the other ones
+var stateLocationAttributes = map[string][]string{
+	"s3":         {"bucket", "key"},
+	"gcs":        {"bucket", "prefix"},
+	"azurerm":    {"storage_account_name", "container_name", "key"},
+	"oss":        {"bucket", "prefix", "key"},
+	"cos":        {"bucket", "prefix", "key"},
+	"local":      {"path"},
+	"consul":     {"address", "path"},
+	"http":       {"address"},
+	"kubernetes": {"namespace", "secret_suffix"},
+	"pg":         {"conn_str", "schema_name"},
+}
+
+// stateKey returns the key of the state within its backend, or "" when the backend has no such thing
+func stateKey(remoteState *remote.RemoteState) string {
+	attribute, ok := stateKeyAttributes[remoteState.Backend]
+	if !ok {
+		return ""
+	}
+	value, ok := remoteState.Config[attribute]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// statePathAttributes are the location attributes of each backend holding a path within the backend, which
+// normalizeStatePath cleans
+var statePathAttributes = map[string][]string{
+	"s3":      {"key", "workspace_key_prefix"},
+	"gcs":     {"prefix"},
+	"azurerm": {"key"},
+	"oss":     {"prefix", "key"},
+	"cos":     {"prefix", "key"},
+	"consul":  {"path"},
+}
+
+// defaultWorkspace is the workspace Terraform uses when none is selected
+const defaultWorkspace = "default"
+
+// defaultS3WorkspaceKeyPrefix is the prefix of the keys of the states of the other workspaces, in an S3 backend
+const defaultS3WorkspaceKeyPrefix = "env:"
+
+// normalizeStatePath cleans a path within a backend, so that `network//terraform.tfstate`,
+// `./network/terraform.tfstate` and `network/terraform.tfstate` are the same location
+func normalizeStatePath(value string) string {
+	if value == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path.Clean("/"+value), "/")
+}
+
+// stateLocation returns a string that is the same for two remote states only when they store the state of `workspace`
+// at the same location. An empty workspace is the default one
+func stateLocation(remoteState *remote.RemoteState, workspace string) string {
+	attributes, ok := stateLocationAttributes[remoteState.Backend]
+	if !ok {
+		// encoding/json sorts map keys, so the same config always gives the same string
+		encoded, _ := json.Marshal(remoteState.Config)
+		location := remoteState.Backend + " " + string(encoded)
+		if workspace != "" && workspace != defaultWorkspace {
+			location += " workspace=" + workspace
+		}
+		return location
+	}
+
+	values := map[string]string{}
+	for _, attribute := range append(append([]string{}, attributes...), statePathAttributes[remoteState.Backend]...) {
+		if value, ok := remoteState.Config[attribute]; ok && value != nil {
+			values[attribute] = fmt.Sprint(value)
+		}
+	}
+	for _, attribute := range statePathAttributes[remoteState.Backend] {
+		values[attribute] = normalizeStatePath(values[attribute])
+	}
+
+	otherWorkspace := workspace != "" && workspace != defaultWorkspace
+	// S3 stores the states of the other workspaces under their own key, which is the one that locates them
+	if otherWorkspace && remoteState.Backend == "s3" {
+		prefix := values["workspace_key_prefix"]
+		if prefix == "" {
+			prefix = defaultS3WorkspaceKeyPrefix
+		}
+		values["key"] = path.Join(prefix, workspace, values["key"])
+		otherWorkspace = false
+	}
+
+	parts := []string{remoteState.Backend}
+	for _, attribute := range attributes {
+		parts = append(parts, fmt.Sprintf("%s=%s", attribute, values[attribute]))
+	}
+	if otherWorkspace {
+		parts = append(parts, "workspace="+workspace)
+	}
+	return strings.Join(parts, " ")
+}
+
+// remoteState evaluates the `remote_state` block of the config at `path`, along with the configs it includes. Nil is
+// returned when there is none
+func (g *Generator) remoteState(path string, terragruntOptions *options.TerragruntOptions) (*remote.RemoteState, error) {
+	parsedConfig, err := g.partialParseConfigFile(path, terragruntOptions, nil, []config.PartialDecodeSectionType{config.RemoteStateBlock})
+	if err != nil {
+		return nil, err
+	}
+	return parsedConfig.RemoteState, nil
+}
+
+// recordStateLocation keeps track of the modules storing their state at the location of `remoteState`, once for each of
+// their `workspaces`, or for the default one when they have none. A state without key is left out, as its location is
+// not one the module chose, so it can't be told apart from the ones of others
+func (g *Generator) recordStateLocation(remoteState *remote.RemoteState, sourcePath string, workspaces []Workspace) {
+	if _, ok := stateKeyAttributes[remoteState.Backend]; ok && stateKey(remoteState) == "" {
+		return
+	}
+	locations := []string{stateLocation(remoteState, defaultWorkspace)}
+	if len(workspaces) > 0 {
+		locations = []string{}
+		for _, workspace := range workspaces {
+			locations = append(locations, stateLocation(remoteState, workspace.Name))
+		}
+	}
+
+	g.stateMtx.Lock()
+	defer g.stateMtx.Unlock()
+	for _, location := range locations {
+		g.stateLocations[location] = append(g.stateLocations[location], sourcePath)
+	}
+}
+
+// recordStateReads keeps track of the state locations the Terraform code of the module in `sourcePath` reads
+func (g *Generator) recordStateReads(sourcePath string, locations []string) {
+	if len(locations) == 0 {
+		return
+	}
+	g.stateMtx.Lock()
+	defer g.stateMtx.Unlock()
+	g.stateReads[sourcePath] = locations
+}
+
+// inferredEdges returns the modules storing their state where the Terraform code of each module reads one, sorted.
+// Locations are compared once normalized by stateLocation
+func (g *Generator) inferredEdges() map[string][]string {
+	edges := map[string][]string{}
+	for sourcePath, locations := range g.stateReads {
+		dependencyDirs := []string{}
+		for _, location := range locations {
+			for _, dependencyDir := range g.stateLocations[location] {
+				if dependencyDir != sourcePath {
+					dependencyDirs = append(dependencyDirs, dependencyDir)
+				}
+			}
+		}
+		if len(dependencyDirs) == 0 {
+			continue
+		}
+		sort.Strings(dependencyDirs)
+		edges[sourcePath] = uniqueStrings(dependencyDirs)
+	}
+	return edges
+}
+
+// duplicateStateLocations returns the modules sharing a state location, by location, sorted
+func (g *Generator) duplicateStateLocations() map[string][]string {
+	duplicates := map[string][]string{}
+	for location, sourcePaths := range g.stateLocations {
+		if len(sourcePaths) < 2 {
+			continue
+		}
+		sorted := append([]string{}, sourcePaths...)
+		sort.Strings(sorted)
+		duplicates[location] = sorted
+	}
+	return duplicates
+}
+
+// checkStateLocations warns about, or fails on, modules storing their state at the same location
+func (g *Generator) checkStateLocations() error {
+	duplicates := g.duplicateStateLocations()
+	locations := []string{}
+	for location := range duplicates {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	messages := []string{}
+	for _, location := range locations {
+		messages = append(messages, fmt.Sprintf("modules %s share the remote state %s", strings.Join(duplicates[location], ", "), location))
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if g.opts.DuplicateStateKeys == DuplicateStateKeysError {
+		return fmt.Errorf("%s", strings.Join(messages, "; "))
+	}
+	for _, message := range messages {
+		log.Warn(message)
+	}
+	return nil
+}
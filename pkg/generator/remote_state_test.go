@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/remote"
+)
+
+func TestStateLocation(t *testing.T) {
+	s3 := func(config map[string]interface{}) *remote.RemoteState {
+		config["bucket"] = "acme-terraform-state"
+		return &remote.RemoteState{Backend: "s3", Config: config}
+	}
+
+	tests := []struct {
+		name       string
+		state      *remote.RemoteState
+		workspace  string
+		other      *remote.RemoteState
+		workspace2 string
+		same       bool
+	}{
+		{
+			name:  "doubled slash",
+			state: s3(map[string]interface{}{"key": "network//terraform.tfstate"}),
+			other: s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			same:  true,
+		},
+		{
+			name:  "leading dot",
+			state: s3(map[string]interface{}{"key": "./network/terraform.tfstate"}),
+			other: s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			same:  true,
+		},
+		{
+			name:  "other key",
+			state: s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			other: s3(map[string]interface{}{"key": "dns/terraform.tfstate"}),
+		},
+		{
+			name:      "default workspace",
+			state:     s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			other:     s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			workspace: defaultWorkspace,
+			same:      true,
+		},
+		{
+			name:       "other workspace",
+			state:      s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			other:      s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			workspace2: "acme",
+		},
+		{
+			name:       "default workspace key prefix",
+			state:      s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			workspace:  "acme",
+			other:      s3(map[string]interface{}{"key": "network/terraform.tfstate", "workspace_key_prefix": "env:/"}),
+			workspace2: "acme",
+			same:       true,
+		},
+		{
+			name:  "workspace key prefix of the other workspaces",
+			state: s3(map[string]interface{}{"key": "env:/acme/network/terraform.tfstate"}),
+			other: s3(map[string]interface{}{"key": "network/terraform.tfstate"}),
+			// The key of the acme workspace
+			workspace2: "acme",
+			same:       true,
+		},
+		{
+			name:       "workspace of a backend without key prefix",
+			state:      &remote.RemoteState{Backend: "gcs", Config: map[string]interface{}{"bucket": "acme", "prefix": "network"}},
+			other:      &remote.RemoteState{Backend: "gcs", Config: map[string]interface{}{"bucket": "acme", "prefix": "network/"}},
+			workspace:  "acme",
+			workspace2: "globex",
+		},
+		{
+			name:  "unknown backend",
+			state: &remote.RemoteState{Backend: "custom", Config: map[string]interface{}{"a": "1", "b": "2"}},
+			other: &remote.RemoteState{Backend: "custom", Config: map[string]interface{}{"b": "2", "a": "1"}},
+			same:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, otherLocation := stateLocation(tt.state, tt.workspace), stateLocation(tt.other, tt.workspace2)
+			if (location == otherLocation) != tt.same {
+				t.Errorf("got locations %q and %q, want them the same: %v", location, otherLocation, tt.same)
+			}
+		})
+	}
+}
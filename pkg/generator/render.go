@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// Pre-populate the map with the environments we want to support as per Gitlab deployment tiers
+// https://docs.gitlab.com/ee/ci/environments/#deployment-tier-of-environments
+var environmentMap = map[string]string{
+	"development": "dev",
+	"staging":     "stg",
+	"production":  "prod",
+}
+
+// DataVersion is the version of the Data format written by this release. Within a version, fields are only ever added,
+// so older data still reads, with the newer fields empty. Removing, renaming or changing the meaning of a field bumps it
+const DataVersion = 1
+
+// Data is what templates are executed with, and what `generate --dump-data` writes as JSON
+type Data struct {
+	// Version of the format, see DataVersion
+	Version int
+	// When true, plans and applies can happen in parallel
+	Needs bool
+	// Modules to generate jobs for, sorted by their source path
+	Dirs []DependencyDirs
+	// GitLab deployment tier, or name, of the environment the config is generated for. Empty means every environment
+	Workload string
+	// Dependency graph between the modules
+	Graph Graph
+}
+
+// Data returns what the template is executed with, from the modules found by the last call to Collect
+func (g *Generator) Data() (*Data, error) {
+	if g.model == nil {
+		return nil, errors.New("Collect must be called before Data")
+	}
+
+	data := &Data{
+		Version: DataVersion,
+		Needs:   g.opts.Parallel,
+		Dirs:    g.model.Modules,
+		Graph:   g.model.Graph,
+	}
+	if g.opts.Environment == "" {
+		data.Workload = ""
+	} else if _, ok := environmentMap[g.opts.Environment]; !ok && g.opts.PreserveEnvironment {
+		data.Workload = g.opts.Environment
+	} else {
+		data.Workload = environmentMap[g.opts.Environment]
+	}
+
+	return data, nil
+}
+
+// Render executes the input template with the modules found by the last call to Collect, writing the result to `w`
+func (g *Generator) Render(w io.Writer) error {
+	data, err := g.Data()
+	if err != nil {
+		return err
+	}
+	return RenderData(w, g.opts.InputTemplate, data)
+}
+
+// RenderData executes the template at `inputTemplate` with `data`, writing the result to `w`
+func RenderData(w io.Writer, inputTemplate string, data *Data) error {
+	// Attempt to parse the input template
+	inputTemplatePath := path.Base(inputTemplate)
+	tpl, err := template.New(inputTemplatePath).Funcs(sprig.TxtFuncMap()).ParseFiles(inputTemplate)
+	if err != nil {
+		return err
+	}
+
+	return tpl.Execute(w, data)
+}
+
+// WriteData writes `data` to `w` as indented JSON
+func WriteData(w io.Writer, data *Data) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// ReadData reads the JSON written by WriteData, refusing versions this release does not understand
+func ReadData(r io.Reader) (*Data, error) {
+	var data Data
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.Version < 1 || data.Version > DataVersion {
+		return nil, fmt.Errorf("unsupported data version %d, this release reads versions 1 to %d", data.Version, DataVersion)
+	}
+	return &data, nil
+}
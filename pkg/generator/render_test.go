@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fill sets every exported field reachable from `value` to a non-zero value, down to `depth` levels of slices and
+// maps, so a field left out of the JSON shows up as a difference
+func fill(value reflect.Value, depth int) {
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				fill(value.Field(i), depth)
+			}
+		}
+	case reflect.String:
+		value.SetString("value")
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(1)
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(1.5)
+	case reflect.Interface:
+		value.Set(reflect.ValueOf("value"))
+	case reflect.Pointer:
+		value.Set(reflect.New(value.Type().Elem()))
+		fill(value.Elem(), depth)
+	case reflect.Slice:
+		if depth == 0 {
+			return
+		}
+		value.Set(reflect.MakeSlice(value.Type(), 1, 1))
+		fill(value.Index(0), depth-1)
+	case reflect.Map:
+		if depth == 0 {
+			return
+		}
+		key := reflect.New(value.Type().Key()).Elem()
+		fill(key, depth-1)
+		elem := reflect.New(value.Type().Elem()).Elem()
+		fill(elem, depth-1)
+		value.Set(reflect.MakeMap(value.Type()))
+		value.SetMapIndex(key, elem)
+	}
+}
+
+func TestDataRoundTrip(t *testing.T) {
+	var data Data
+	fill(reflect.ValueOf(&data).Elem(), 3)
+	data.Version = DataVersion
+
+	var out bytes.Buffer
+	if err := WriteData(&out, &data); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadData(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, &data) {
+		t.Errorf("data differs once read back\ngot:  %+v\nwant: %+v", got, &data)
+	}
+}
+
+// Rendering data dumped with `generate --dump-data` must give the same output as rendering it right away
+func TestRenderDumpedData(t *testing.T) {
+	for _, root := range []string{"projects", "projects/matrix", "projects/metadata"} {
+		t.Run(root, func(t *testing.T) {
+			g := newTestGenerator(t, root, groupsTemplate)
+			if _, err := g.Collect(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			var want bytes.Buffer
+			if err := g.Render(&want); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := g.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dumped bytes.Buffer
+			if err := WriteData(&dumped, data); err != nil {
+				t.Fatal(err)
+			}
+			read, err := ReadData(&dumped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := RenderData(&got, g.opts.InputTemplate, read); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("rendering dumped data differs\ngot:\n%s\nwant:\n%s", got.String(), want.String())
+			}
+		})
+	}
+}
+
+func TestReadDataVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		valid bool
+	}{
+		{name: "current", json: `{"Version": 1}`, valid: true},
+		{name: "missing", json: `{"Dirs": []}`},
+		{name: "zero", json: `{"Version": 0}`},
+		{name: "newer", json: fmt.Sprintf(`{"Version": %d}`, DataVersion+1)},
+		{name: "not a number", json: `{"Version": "1"}`},
+		{name: "malformed", json: `{"Version": 1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadData(strings.NewReader(tt.json)); (err == nil) != tt.valid {
+				t.Errorf("got error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
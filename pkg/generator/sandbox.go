@@ -0,0 +1,227 @@
+package generator
+
+// Some Terragrunt functions have side effects: they run commands, or need cloud credentials to answer. Generating the
+// pipeline doesn't need their real results, so the sandbox replaces them with deterministic stubs and records what was
+// asked of them. This lets the generator run offline, in an unprivileged job, and give the same output every time.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	log "github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// Default return values of the sandboxed functions. They can be overridden with `--sandbox-value`
+var sandboxDefaults = map[string]string{
+	"run_cmd":                         "",
+	"get_aws_account_id":              "000000000000",
+	"get_aws_caller_identity_arn":     "arn:aws:iam::000000000000:user/sandbox",
+	"get_aws_caller_identity_user_id": "SANDBOX",
+	// An empty document decodes with both `jsondecode` and `yamldecode`
+	"sops_decrypt_file": "{}",
+}
+
+// Flags of `run_cmd` that change how Terragrunt runs the command, but not which command is run
+var runCmdFlags = map[string]bool{
+	"--terragrunt-quiet":        true,
+	"--terragrunt-global-cache": true,
+}
+
+// SandboxCall is a single call to a sandboxed function
+type SandboxCall struct {
+	// Name of the function called
+	Function string
+	// Arguments the function was called with
+	Args []string
+	// Config file the call was made from
+	File string
+	// Stubbed value returned to the config
+	Result string
+}
+
+type sandbox struct {
+	enabled bool
+	// Return values by function name, or by function name and arguments (`run_cmd:echo hello`)
+	values map[string]string
+
+	mtx   sync.Mutex
+	calls map[string]SandboxCall
+}
+
+func newSandbox(enabled bool, values map[string]string) *sandbox {
+	return &sandbox{enabled: enabled, values: values, calls: map[string]SandboxCall{}}
+}
+
+// applySandbox replaces the functions with side effects in `evalContext` with their stubs, if the sandbox is enabled
+func (g *Generator) applySandbox(evalContext *hcl.EvalContext, filename string, terragruntOptions *options.TerragruntOptions) {
+	if !g.sandbox.enabled {
+		return
+	}
+
+	for name := range sandboxDefaults {
+		evalContext.Functions[name] = g.sandbox.stub(name, filename)
+	}
+	evalContext.Functions["read_terragrunt_config"] = g.readTerragruntConfigAsFuncImpl(terragruntOptions)
+}
+
+// stub returns a function that records its arguments and returns the configured value for `name`
+func (s *sandbox) stub(name string, filename string) function.Function {
+	return function.New(&function.Spec{
+		VarParam: &function.Parameter{Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			strArgs := []string{}
+			for _, arg := range args {
+				if name == "run_cmd" && runCmdFlags[arg.AsString()] {
+					continue
+				}
+				strArgs = append(strArgs, arg.AsString())
+			}
+
+			result := s.value(name, strArgs)
+			s.record(SandboxCall{Function: name, Args: strArgs, File: filename, Result: result})
+
+			return cty.StringVal(result), nil
+		},
+	})
+}
+
+// value looks up the return value for a call, most specific match first
+func (s *sandbox) value(name string, args []string) string {
+	if value, ok := s.values[name+":"+strings.Join(args, " ")]; ok {
+		return value
+	}
+	if value, ok := s.values[name]; ok {
+		return value
+	}
+	return sandboxDefaults[name]
+}
+
+func (s *sandbox) record(call SandboxCall) {
+	log.Debugf("Sandboxed %s(%s) in %s returned %q", call.Function, strings.Join(call.Args, ", "), call.File, call.Result)
+
+	key := fmt.Sprintf("%s|%s|%q", call.File, call.Function, call.Args)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.calls[key] = call
+}
+
+// Calls returns every distinct call made to a sandboxed function, in a stable order
+func (s *sandbox) Calls() []SandboxCall {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	calls := make([]SandboxCall, 0, len(s.calls))
+	for _, call := range s.calls {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].File != calls[j].File {
+			return calls[i].File < calls[j].File
+		}
+		if calls[i].Function != calls[j].Function {
+			return calls[i].Function < calls[j].Function
+		}
+		return strings.Join(calls[i].Args, " ") < strings.Join(calls[j].Args, " ")
+	})
+	return calls
+}
+
+// writeRecord writes every call made to a sandboxed function to `path` as JSON
+func (s *sandbox) writeRecord(path string) error {
+	out, err := json.MarshalIndent(s.Calls(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0644)
+}
+
+// terragruntConfigForRead is the part of a config `read_terragrunt_config` exposes while sandboxed
+type terragruntConfigForRead struct {
+	Inputs *cty.Value `hcl:"inputs,attr"`
+	Remain hcl.Body   `hcl:",remain"`
+}
+
+// readTerragruntConfigAsFuncImpl creates a sandboxed `read_terragrunt_config`. Terragrunt's own implementation fully
+// parses the target config with the real functions, so this one only exposes its `locals` and `inputs`, evaluated
+// through the sandbox.
+func (g *Generator) readTerragruntConfigAsFuncImpl(terragruntOptions *options.TerragruntOptions) function.Function {
+	return function.New(&function.Spec{
+		Params:   []function.Parameter{{Type: cty.String}},
+		VarParam: &function.Parameter{Type: cty.DynamicPseudoType},
+		Type:     function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if len(args) > 2 {
+				return cty.NilVal, config.WrongNumberOfParams{Func: "read_terragrunt_config", Expected: "1 or 2", Actual: len(args)}
+			}
+
+			targetConfig := args[0].AsString()
+			if !filepath.IsAbs(targetConfig) {
+				targetConfig = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), targetConfig)
+			}
+			if util.IsDir(targetConfig) {
+				targetConfig = config.GetDefaultConfigPath(targetConfig)
+			}
+
+			if !util.FileExists(targetConfig) {
+				if len(args) == 2 {
+					return args[1], nil
+				}
+				return cty.NilVal, config.TerragruntConfigNotFound{Path: targetConfig}
+			}
+
+			return g.readTerragruntConfig(targetConfig, terragruntOptions.Clone(targetConfig))
+		},
+	})
+}
+
+// readTerragruntConfig evaluates the `locals` and `inputs` of the config at `path`
+func (g *Generator) readTerragruntConfig(path string, terragruntOptions *options.TerragruntOptions) (cty.Value, error) {
+	parsed, err := g.store.file(path)
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	localsAsCty, trackInclude, err := g.decodeBaseBlocks(terragruntOptions, parsed, path, nil, nil)
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	evalContext, err := g.createEvalContext(path, terragruntOptions, config.EvalContextExtensions{
+		Locals:       localsAsCty,
+		TrackInclude: trackInclude,
+	})
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	decoded := terragruntConfigForRead{}
+	if diags := gohcl.DecodeBody(parsed.file.Body, evalContext, &decoded); diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	output := map[string]cty.Value{
+		config.MetadataLocals: cty.EmptyObjectVal,
+		config.MetadataInputs: cty.EmptyObjectVal,
+	}
+	if *localsAsCty != cty.NilVal {
+		output[config.MetadataLocals] = *localsAsCty
+	}
+	if decoded.Inputs != nil {
+		output[config.MetadataInputs] = *decoded.Inputs
+	}
+	return cty.ObjectVal(output), nil
+}
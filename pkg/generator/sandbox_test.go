@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		calls   []string
+		changes string
+		ref     string
+	}{
+		{
+			name: "defaults",
+			calls: []string{
+				"child/terragrunt.hcl run_cmd(echo generated) = ",
+				"terragrunt.hcl run_cmd(echo v1.2.3) = ",
+			},
+			changes: "child/**/*,terragrunt.hcl,child/dev.tfvars,child/.json",
+		},
+		{
+			name:   "function value",
+			values: map[string]string{"run_cmd": "stub"},
+			calls: []string{
+				"child/terragrunt.hcl run_cmd(echo generated) = stub",
+				"terragrunt.hcl run_cmd(echo v1.2.3) = stub",
+			},
+			changes: "child/**/*,terragrunt.hcl,child/dev.tfvars,child/stub.json",
+			ref:     "stub",
+		},
+		{
+			// Flags changing how Terragrunt runs the command are not part of the arguments matched
+			name:   "arguments value",
+			values: map[string]string{"run_cmd": "stub", "run_cmd:echo v1.2.3": "v1.2.3"},
+			calls: []string{
+				"child/terragrunt.hcl run_cmd(echo generated) = stub",
+				"terragrunt.hcl run_cmd(echo v1.2.3) = v1.2.3",
+			},
+			changes: "child/**/*,terragrunt.hcl,child/dev.tfvars,child/stub.json",
+			ref:     "v1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, "projects/sandbox", func(opts *Options) {
+				opts.SandboxValues = tt.values
+			})
+			model, err := g.Collect(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			calls := []string{}
+			for _, call := range g.SandboxCalls() {
+				file := filepath.ToSlash(strings.TrimPrefix(call.File, g.root))
+				calls = append(calls, file+" "+call.Function+"("+strings.Join(call.Args, " ")+") = "+call.Result)
+			}
+			if !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("calls: got %q, want %q", calls, tt.calls)
+			}
+
+			record := filepath.Join(t.TempDir(), "sandbox.json")
+			if err := g.WriteSandboxRecord(record); err != nil {
+				t.Fatal(err)
+			}
+			contents, err := os.ReadFile(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var recorded []SandboxCall
+			if err := json.Unmarshal(contents, &recorded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(recorded, g.SandboxCalls()) {
+				t.Errorf("record: got %+v, want %+v", recorded, g.SandboxCalls())
+			}
+
+			for _, module := range model.Modules {
+				if module.SourcePath != "child" {
+					continue
+				}
+				if changes := strings.Join(module.Dependencies, ","); changes != tt.changes {
+					t.Errorf("changes: got %s, want %s", changes, tt.changes)
+				}
+				if module.Source == nil || module.Source.Ref != tt.ref {
+					t.Errorf("source: got %+v, want ref %q", module.Source, tt.ref)
+				}
+			}
+		})
+	}
+}
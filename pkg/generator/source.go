@@ -0,0 +1,176 @@
+package generator
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-getter"
+)
+
+// Types of module sources
+const (
+	SourceTypeLocal    = "local"
+	SourceTypeGit      = "git"
+	SourceTypeRegistry = "registry"
+	SourceTypeS3       = "s3"
+	SourceTypeGCS      = "gcs"
+	SourceTypeHTTP     = "http"
+	SourceTypeOther    = "other"
+)
+
+// defaultRegistryHost is the registry `tfr:///` sources without a host are fetched from
+const defaultRegistryHost = "registry.terraform.io"
+
+// Source is the parsed `terraform.source` of a module
+type Source struct {
+	// Type of the source; local, git, registry, s3, gcs, http, or other
+	Type string
+	// Address of the repository, bucket, registry module or archive, without subdirectory nor ref. For local sources,
+	// the directory, relative to the root when it is within it
+	URL string
+	// Subdirectory within the source, after `//`
+	Subdir string
+	// Git ref, or registry version
+	Ref string
+	// Source as set in the config
+	Raw string
+	// Prefix of the remote source the local directory was mapped from with the source map. Empty when not mapped
+	MappedFrom string
+}
+
+// forcedGetterPattern matches the `getter::` prefix forcing the getter of a go-getter source
+var forcedGetterPattern = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+
+// windowsPathPattern matches paths beginning with a drive letter
+var windowsPathPattern = regexp.MustCompile(`^[A-Z]:`)
+
+// parseSource parses the `terraform.source` of the config at `configPath`
+func (g *Generator) parseSource(raw string, configPath string) (*Source, error) {
+	source := &Source{Raw: raw}
+
+	if dir, subdir, prefix, ok := g.mapSource(raw, configPath); ok {
+		source.Type = SourceTypeLocal
+		source.URL = g.displayPath(dir)
+		source.Subdir = subdir
+		source.MappedFrom = prefix
+		return source, nil
+	}
+
+	// Terragrunt fetches `tfr://` sources itself, so go-getter knows nothing about them
+	if strings.HasPrefix(raw, "tfr://") {
+		address, subdir := getter.SourceDirSubdir(raw)
+		parsed, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		host := parsed.Host
+		if host == "" {
+			host = defaultRegistryHost
+		}
+		source.Type = SourceTypeRegistry
+		source.URL = host + parsed.Path
+		source.Subdir = subdir
+		source.Ref = parsed.Query().Get("version")
+		return source, nil
+	}
+
+	detected, err := getter.Detect(raw, filepath.Dir(configPath), getter.Detectors)
+	if err != nil {
+		return nil, err
+	}
+
+	forcedGetter := ""
+	if matches := forcedGetterPattern.FindStringSubmatch(detected); matches != nil {
+		forcedGetter, detected = matches[1], matches[2]
+	}
+	address, subdir := getter.SourceDirSubdir(detected)
+	source.Subdir = subdir
+
+	if strings.HasPrefix(address, "file://") || windowsPathPattern.MatchString(address) {
+		source.Type = SourceTypeLocal
+		source.URL = g.displayPath(filepath.Clean(strings.TrimPrefix(address, "file://")))
+		return source, nil
+	}
+
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	query := parsed.Query()
+	scheme := parsed.Scheme
+	if forcedGetter != "" {
+		scheme = forcedGetter
+	}
+
+	switch scheme {
+	case "git", "ssh":
+		source.Type = SourceTypeGit
+		source.Ref = query.Get("ref")
+		query.Del("ref")
+	case "s3":
+		source.Type = SourceTypeS3
+	case "gcs", "gs":
+		source.Type = SourceTypeGCS
+	case "http", "https":
+		source.Type = SourceTypeHTTP
+	default:
+		source.Type = SourceTypeOther
+	}
+	parsed.RawQuery = query.Encode()
+	source.URL = parsed.String()
+
+	return source, nil
+}
+
+// mapSource returns the local directory the source map maps `raw` to, along with the subdirectory within it and the
+// matching prefix. Prefixes match whole path segments of the source, without subdirectory nor query, either as set in
+// the config or as normalized by go-getter. The longest matching prefix wins
+func (g *Generator) mapSource(raw string, configPath string) (string, string, string, bool) {
+	if len(g.opts.SourceMap) == 0 {
+		return "", "", "", false
+	}
+
+	candidates := []string{raw}
+	if detected, err := getter.Detect(raw, filepath.Dir(configPath), getter.Detectors); err == nil && detected != raw {
+		candidates = append(candidates, detected)
+	}
+
+	prefixes := []string{}
+	for prefix := range g.opts.SourceMap {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	for _, candidate := range candidates {
+		address, subdir := getter.SourceDirSubdir(candidate)
+		address, _, _ = strings.Cut(address, "?")
+
+		for _, prefix := range prefixes {
+			trimmedPrefix := strings.TrimSuffix(prefix, "/")
+			if address != trimmedPrefix && !strings.HasPrefix(address, trimmedPrefix+"/") {
+				continue
+			}
+
+			dir := g.opts.SourceMap[prefix]
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(g.root, dir)
+			}
+			dir = filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(address, trimmedPrefix)))
+			return filepath.Clean(dir), subdir, prefix, true
+		}
+	}
+	return "", "", "", false
+}
+
+// absoluteDir returns the absolute directory of a local source
+func (g *Generator) absoluteDir(source *Source) string {
+	if filepath.IsAbs(source.URL) {
+		return source.URL
+	}
+	return filepath.Join(g.root, filepath.FromSlash(source.URL))
+}
@@ -0,0 +1,310 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	log "github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// TerraformModule is what the Terraform code a module runs declares, when that code lives in the repo
+type TerraformModule struct {
+	// Directory of the Terraform code, relative to the root when it is within it
+	Dir string
+	// Version constraints of `required_version`
+	RequiredVersion []string
+	// Providers of `required_providers`, by local name
+	RequiredProviders map[string]ProviderRequirement
+	// Type of the `backend` block, or `cloud` for a `cloud` block. Empty when the code sets neither
+	Backend string
+	// Declared variables, sorted by name
+	Variables []TerraformVariable
+	// Declared outputs, sorted by name
+	Outputs []TerraformOutput
+	// Tree of the local modules the code calls, sorted by name
+	ModuleCalls []ModuleCall
+
+	// Locations, as given by stateLocation, of the states read by `terraform_remote_state` data sources
+	remoteStateReads []string
+}
+
+// ProviderRequirement is an entry of `required_providers`
+type ProviderRequirement struct {
+	// Source address, like `hashicorp/aws`. Empty for legacy entries that only set a version
+	Source string
+	// Version constraints of the provider
+	VersionConstraints []string
+}
+
+// TerraformVariable is a `variable` block
+type TerraformVariable struct {
+	Name        string
+	Type        string
+	Description string
+	// Approximation of the default value. Nil when there is none
+	Default   interface{}
+	Required  bool
+	Sensitive bool
+}
+
+// TerraformOutput is an `output` block
+type TerraformOutput struct {
+	Name        string
+	Description string
+	Sensitive   bool
+}
+
+// ProviderSet returns the required providers as a stable string, one `name=source constraints` entry per provider, for
+// example to key a plugin cache shared by the modules requiring the same providers
+func (m *TerraformModule) ProviderSet() string {
+	entries := []string{}
+	for name, provider := range m.RequiredProviders {
+		entries = append(entries, fmt.Sprintf("%s=%s %s", name, provider.Source, strings.Join(provider.VersionConstraints, ",")))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ";")
+}
+
+// terraformDir returns the absolute directory of the Terraform code the config at `configPath` runs, given its parsed
+// `terraform.source`, or an empty string when that code does not live in the repo. Terragrunt runs the module directory
+// itself when there is no source
+func (g *Generator) terraformDir(configPath string, source *Source) string {
+	dir := filepath.Dir(configPath)
+	if source != nil {
+		if source.Type != SourceTypeLocal {
+			return ""
+		}
+		dir = filepath.Join(g.absoluteDir(source), source.Subdir)
+	}
+	if !tfconfig.IsModuleDir(dir) {
+		return ""
+	}
+	return dir
+}
+
+// terraformModule loads the Terraform code in `dir`. Code shared by several modules is only loaded once per run
+func (g *Generator) terraformModule(dir string) (*TerraformModule, error) {
+	g.terraformMtx.RLock()
+	module, ok := g.terraformModules[dir]
+	g.terraformMtx.RUnlock()
+	if ok {
+		return module, nil
+	}
+
+	res, err, _ := g.terraformGroup.Do("terraform:"+dir, func() (interface{}, error) {
+		g.terraformMtx.RLock()
+		module, ok := g.terraformModules[dir]
+		g.terraformMtx.RUnlock()
+		if ok {
+			return module, nil
+		}
+
+		module, err := g.loadTerraformModule(dir)
+		if err != nil {
+			return nil, err
+		}
+		g.terraformMtx.Lock()
+		g.terraformModules[dir] = module
+		g.terraformMtx.Unlock()
+		return module, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*TerraformModule), nil
+}
+
+// loadTerraformModule loads the Terraform code in `dir`, without going through the cache
+func (g *Generator) loadTerraformModule(dir string) (*TerraformModule, error) {
+	tfModule, diags := tfconfig.LoadModule(dir)
+	if diags.HasErrors() {
+		return nil, errors.New(diags.Error())
+	}
+	files, err := parseTerraformFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	backend := terraformBackend(files)
+	moduleCalls, err := g.moduleCallTree(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	module := &TerraformModule{
+		Dir:               g.displayPath(dir),
+		RequiredVersion:   tfModule.RequiredCore,
+		RequiredProviders: map[string]ProviderRequirement{},
+		Backend:           backend,
+		Variables:         []TerraformVariable{},
+		Outputs:           []TerraformOutput{},
+		ModuleCalls:       moduleCalls,
+		remoteStateReads:  terraformRemoteStateReads(dir, files),
+	}
+	for name, provider := range tfModule.RequiredProviders {
+		module.RequiredProviders[name] = ProviderRequirement{
+			Source:             provider.Source,
+			VersionConstraints: provider.VersionConstraints,
+		}
+	}
+	for _, variable := range tfModule.Variables {
+		module.Variables = append(module.Variables, TerraformVariable{
+			Name:        variable.Name,
+			Type:        variable.Type,
+			Description: variable.Description,
+			Default:     variable.Default,
+			Required:    variable.Required,
+			Sensitive:   variable.Sensitive,
+		})
+	}
+	sort.Slice(module.Variables, func(i, j int) bool {
+		return module.Variables[i].Name < module.Variables[j].Name
+	})
+	for _, output := range tfModule.Outputs {
+		module.Outputs = append(module.Outputs, TerraformOutput{
+			Name:        output.Name,
+			Description: output.Description,
+			Sensitive:   output.Sensitive,
+		})
+	}
+	sort.Slice(module.Outputs, func(i, j int) bool {
+		return module.Outputs[i].Name < module.Outputs[j].Name
+	})
+
+	return module, nil
+}
+
+var terraformFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}},
+}
+
+var terraformBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "backend", LabelNames: []string{"type"}},
+		{Type: "cloud"},
+	},
+}
+
+// parseTerraformFiles parses the `.tf` and `.tf.json` files in `dir`
+func parseTerraformFiles(dir string) ([]*hcl.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	files := []*hcl.File{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		switch {
+		case strings.HasSuffix(entry.Name(), ".tf"):
+			file, diags = parser.ParseHCLFile(path)
+		case strings.HasSuffix(entry.Name(), ".tf.json"):
+			file, diags = parser.ParseJSONFile(path)
+		default:
+			continue
+		}
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// terraformBackend returns the backend type the Terraform `files` configure, which tfconfig does not expose
+func terraformBackend(files []*hcl.File) string {
+	backend := ""
+	for _, file := range files {
+		content, _, _ := file.Body.PartialContent(terraformFileSchema)
+		for _, terraformBlock := range content.Blocks {
+			terraformContent, _, _ := terraformBlock.Body.PartialContent(terraformBlockSchema)
+			for _, block := range terraformContent.Blocks {
+				if block.Type == "cloud" {
+					backend = "cloud"
+				} else {
+					backend = block.Labels[0]
+				}
+			}
+		}
+	}
+	return backend
+}
+
+var dataSourceFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "data", LabelNames: []string{"type", "name"}}},
+}
+
+var remoteStateDataSourceSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "backend", Required: true},
+		{Name: "config"},
+		{Name: "workspace"},
+	},
+}
+
+// terraformRemoteStateReads returns the locations of the states read by the `terraform_remote_state` data sources of
+// the Terraform `files` in `dir`. Only the data sources whose backend, config and workspace are literals, without any
+// variable, can be located
+func terraformRemoteStateReads(dir string, files []*hcl.File) []string {
+	locations := []string{}
+	for _, file := range files {
+		content, _, _ := file.Body.PartialContent(dataSourceFileSchema)
+		for _, block := range content.Blocks {
+			if block.Labels[0] != "terraform_remote_state" {
+				continue
+			}
+			name := "data.terraform_remote_state." + block.Labels[1]
+
+			attributes, _, diags := block.Body.PartialContent(remoteStateDataSourceSchema)
+			if diags.HasErrors() {
+				log.Debug("Could not decode ", name, " in ", dir, ": ", diags)
+				continue
+			}
+			backend, diags := attributes.Attributes["backend"].Expr.Value(nil)
+			if diags.HasErrors() || backend.Type() != cty.String || backend.IsNull() {
+				log.Debug("Could not evaluate the backend of ", name, " in ", dir, ": ", diags)
+				continue
+			}
+			remoteState := &remote.RemoteState{Backend: backend.AsString(), Config: map[string]interface{}{}}
+			if attribute, ok := attributes.Attributes["config"]; ok {
+				value, diags := attribute.Expr.Value(nil)
+				if diags.HasErrors() {
+					log.Debug("Could not evaluate the config of ", name, " in ", dir, ": ", diags)
+					continue
+				}
+				config, err := parseCtyValueToMap(value)
+				if err != nil {
+					log.Debug("Could not evaluate the config of ", name, " in ", dir, ": ", err)
+					continue
+				}
+				remoteState.Config = config
+			}
+			workspace := defaultWorkspace
+			if attribute, ok := attributes.Attributes["workspace"]; ok {
+				value, diags := attribute.Expr.Value(nil)
+				if diags.HasErrors() || value.Type() != cty.String || value.IsNull() {
+					log.Debug("Could not evaluate the workspace of ", name, " in ", dir, ": ", diags)
+					continue
+				}
+				workspace = value.AsString()
+			}
+			locations = append(locations, stateLocation(remoteState, workspace))
+		}
+	}
+	return locations
+}
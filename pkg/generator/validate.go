@@ -0,0 +1,494 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// Names of the checks run by Validate
+const (
+	CheckParse               = "parse"
+	CheckInclude             = "include"
+	CheckDependency          = "dependency"
+	CheckExtraDependency     = "extra-dependency"
+	CheckVarFile             = "var-file"
+	CheckCycle               = "cycle"
+	CheckDuplicateSourcePath = "duplicate-source-path"
+	CheckRemoteState         = "remote-state"
+	CheckDuplicateStateKey   = "duplicate-state-key"
+	CheckModuleCycle         = "module-cycle"
+)
+
+// blockRef selects blocks by type and, unless Labels is nil, by labels
+type blockRef struct {
+	Type   string
+	Labels []string
+}
+
+// findAttributeLine returns the line of the attribute `name` within the blocks nested as `blocks` in `file`, or 0 when
+// it is not set there. Only native syntax files carry line information
+func findAttributeLine(file *hcl.File, name string, blocks ...blockRef) int {
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return 0
+	}
+	return findAttributeLineInBody(body, name, blocks)
+}
+
+func findAttributeLineInBody(body *hclsyntax.Body, name string, blocks []blockRef) int {
+	if len(blocks) == 0 {
+		if attr, ok := body.Attributes[name]; ok {
+			return attr.SrcRange.Start.Line
+		}
+		return 0
+	}
+
+	for _, block := range body.Blocks {
+		if block.Type != blocks[0].Type {
+			continue
+		}
+		if blocks[0].Labels != nil && !slices.Equal(block.Labels, blocks[0].Labels) {
+			continue
+		}
+		if line := findAttributeLineInBody(block.Body, name, blocks[1:]); line != 0 {
+			return line
+		}
+	}
+	return 0
+}
+
+// includePath returns the absolute path of the config `include` points at, from the config at `path`
+func includePath(path string, include config.IncludeConfig) string {
+	if filepath.IsAbs(include.Path) {
+		return filepath.Clean(include.Path)
+	}
+	return filepath.Join(filepath.Dir(path), include.Path)
+}
+
+// relativePath returns `path` relative to the root, with Unix separators
+func (g *Generator) relativePath(path string) string {
+	return filepath.ToSlash(strings.TrimPrefix(path, g.root))
+}
+
+// displayPath returns `path` relative to the root when it is within it, "." for the root itself, and unchanged otherwise
+func (g *Generator) displayPath(path string) string {
+	if path == strings.TrimSuffix(g.root, string(filepath.Separator)) {
+		return "."
+	}
+	if strings.HasPrefix(path, g.root) {
+		return g.relativePath(path)
+	}
+	return path
+}
+
+// validator gathers the findings of a Validate or Lint run
+type validator struct {
+	g *Generator
+
+	mtx      sync.Mutex
+	findings []Finding
+	// Line of the attribute declaring each edge, by config path and then dependency config path
+	edgeLines map[string]map[string]int
+}
+
+func (v *validator) add(check string, severity Severity, path string, line int, format string, args ...interface{}) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.findings = append(v.findings, Finding{
+		Check:    check,
+		Severity: severity,
+		File:     v.g.relativePath(path),
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// addError reports `err` as an error, at the location of its first HCL diagnostic when there is one
+func (v *validator) addError(check string, path string, line int, err error) {
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) && len(diags) > 0 {
+		diag := diags[0]
+		message := diag.Summary
+		if diag.Detail != "" {
+			message = fmt.Sprintf("%s; %s", diag.Summary, diag.Detail)
+		}
+		if diag.Subject != nil {
+			path, line = diag.Subject.Filename, diag.Subject.Start.Line
+		}
+		v.add(check, SeverityError, path, line, "%s", message)
+		return
+	}
+	v.add(check, SeverityError, path, line, "%s", err)
+}
+
+// locate returns the file, among the config at `path` and the configs it includes, setting the attribute `name`
+// within `blocks`, along with the line it is set at
+func (v *validator) locate(path string, includes []config.IncludeConfig, name string, blocks ...blockRef) (string, int) {
+	candidates := []string{path}
+	for _, include := range includes {
+		candidates = append(candidates, includePath(path, include))
+	}
+
+	for _, candidate := range candidates {
+		stored, err := v.g.store.file(candidate)
+		if err != nil {
+			continue
+		}
+		if line := findAttributeLine(stored.file, name, blocks...); line != 0 {
+			return candidate, line
+		}
+	}
+	return path, 0
+}
+
+func (v *validator) setEdgeLine(path string, dependencyConfigPath string, line int) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if v.edgeLines[path] == nil {
+		v.edgeLines[path] = map[string]int{}
+	}
+	v.edgeLines[path][dependencyConfigPath] = line
+}
+
+// resolveDependencyConfigPath returns the config a `dependency` or `dependencies` path of the config at `path` points at
+func resolveDependencyConfigPath(path string, dependencyPath string) string {
+	if !filepath.IsAbs(dependencyPath) {
+		dependencyPath = filepath.Join(filepath.Dir(path), dependencyPath)
+	}
+	if util.IsFile(dependencyPath) {
+		return filepath.Clean(dependencyPath)
+	}
+	return config.GetDefaultConfigPath(filepath.Clean(dependencyPath))
+}
+
+// validateModule runs the checks of a single config
+func (v *validator) validateModule(path string) {
+	g := v.g
+
+	terragruntOptions, err := g.newTerragruntOptions(path)
+	if err != nil {
+		v.addError(CheckParse, path, 0, err)
+		return
+	}
+	terragruntOptions.OriginalTerragruntConfigPath = path
+
+	role, includes, err := g.parseModule(path, terragruntOptions)
+	if err != nil {
+		v.addError(CheckParse, path, 0, err)
+		return
+	}
+
+	// Every included config must parse, or nothing else about the module can be checked
+	includesParse := true
+	for _, include := range includes {
+		if _, err := g.store.file(includePath(path, include)); err != nil {
+			_, line := v.locate(path, nil, "path", blockRef{Type: "include", Labels: []string{include.Name}})
+			if line == 0 {
+				_, line = v.locate(path, nil, "path", blockRef{Type: "include", Labels: []string{}})
+			}
+			v.add(CheckInclude, SeverityError, path, line, "included config %s does not parse: %s", include.Path, err)
+			includesParse = false
+		}
+	}
+	if !includesParse || role != roleModule {
+		return
+	}
+
+	decodeTypes := []config.PartialDecodeSectionType{
+		config.DependencyBlock,
+		config.DependenciesBlock,
+		config.TerraformBlock,
+	}
+	parsedConfig, err := g.partialParseConfigFile(path, terragruntOptions, nil, decodeTypes)
+	if err != nil {
+		v.addError(CheckParse, path, 0, err)
+		return
+	}
+
+	// Two modules must not store their state at the same location
+	remoteState, err := g.remoteState(path, terragruntOptions)
+	if err != nil {
+		v.add(CheckRemoteState, SeverityWarning, path, 0, "remote_state could not be evaluated: %s", err)
+	} else if remoteState != nil {
+		g.recordStateLocation(remoteState, path, nil)
+	}
+
+	// Every `dependency` and `dependencies` path must point at an existing module
+	fromDependencyBlocks := map[string]bool{}
+	for _, dependency := range parsedConfig.TerragruntDependencies {
+		fromDependencyBlocks[dependency.ConfigPath] = true
+		file, line := v.locate(path, includes, "config_path", blockRef{Type: "dependency", Labels: []string{dependency.Name}})
+		dependencyConfigPath := resolveDependencyConfigPath(path, dependency.ConfigPath)
+		v.setEdgeLine(path, dependencyConfigPath, line)
+		if !util.FileExists(dependencyConfigPath) {
+			v.add(CheckDependency, SeverityError, file, line, "dependency %q points at %s, which is not a module", dependency.Name, dependency.ConfigPath)
+		}
+	}
+	if parsedConfig.Dependencies != nil {
+		g.recordEdges(path, parsedConfig.Dependencies.Paths)
+
+		for _, dependencyPath := range parsedConfig.Dependencies.Paths {
+			if fromDependencyBlocks[dependencyPath] || fromDependencyBlocks[filepath.Join(dependencyPath, config.DefaultTerragruntConfigPath)] {
+				continue
+			}
+			file, line := v.locate(path, includes, "paths", blockRef{Type: "dependencies"})
+			dependencyConfigPath := resolveDependencyConfigPath(path, dependencyPath)
+			v.setEdgeLine(path, dependencyConfigPath, line)
+			if !util.FileExists(dependencyConfigPath) {
+				v.add(CheckDependency, SeverityError, file, line, "dependencies path %s is not a module", dependencyPath)
+			}
+		}
+	}
+
+	// Extra dependencies only affect change tracking, so missing ones are warnings
+	locals, err := g.parseLocals(path, terragruntOptions, nil)
+	if err != nil {
+		v.addError(CheckParse, path, 0, err)
+	}
+	// Generation goes on without the settings of the included configs whose locals can't be evaluated
+	uninheritedParents := make([]string, 0, len(locals.uninheritedParents))
+	for parentPath := range locals.uninheritedParents {
+		uninheritedParents = append(uninheritedParents, parentPath)
+	}
+	sort.Strings(uninheritedParents)
+	for _, parentPath := range uninheritedParents {
+		line := 0
+		for _, include := range includes {
+			if includePath(path, include) == parentPath {
+				_, line = v.locate(path, nil, "path", blockRef{Type: "include", Labels: []string{include.Name}})
+			}
+		}
+		v.add(CheckInclude, SeverityWarning, path, line, "settings of included config %s are not inherited, as its locals could not be evaluated: %s", g.relativePath(parentPath), locals.uninheritedParents[parentPath])
+	}
+	for _, extraDependency := range locals.ExtraGitlabCiDependencies {
+		if extraDependency == "" || v.pathExists(path, extraDependency) {
+			continue
+		}
+		file, line := v.locate(path, includes, "extra_atlantis_dependencies", blockRef{Type: "locals"})
+		v.add(CheckExtraDependency, SeverityWarning, file, line, "extra dependency %s does not match any file", extraDependency)
+	}
+	// Unlike the ones above, generation fails on these
+	for _, extraDependency := range locals.ExtraDependencies {
+		if extraDependency == "" || v.pathExists(path, extraDependency) {
+			continue
+		}
+		file, line := v.locate(path, includes, "gitlab_ci_extra_dependencies", blockRef{Type: "locals"})
+		v.add(CheckExtraDependency, SeverityError, file, line, "extra dependency %s does not match any file", extraDependency)
+	}
+	if _, err := ignoreChangesRules(locals.IgnoreChanges, "", path); err != nil {
+		file, line := v.locate(path, includes, "gitlab_ci_ignore_changes", blockRef{Type: "locals"})
+		v.add(CheckParse, SeverityError, file, line, "gitlab_ci_ignore_changes: %s", errors.Unwrap(err))
+	}
+	if _, err := g.dependencyPatterns(path, nil, append(locals.IgnoreDependencies.Changes, locals.IgnoreDependencies.Cascade...)); err != nil {
+		file, line := v.locate(path, includes, "gitlab_ci_ignore_dependencies", blockRef{Type: "locals"})
+		v.add(CheckParse, SeverityError, file, line, "gitlab_ci_ignore_dependencies: %s", errors.Unwrap(err))
+	}
+
+	// Local Terraform modules must not call each other in a cycle
+	var source *Source
+	var sourceErr error
+	if parsedConfig.Terraform != nil && parsedConfig.Terraform.Source != nil {
+		source, sourceErr = g.parseSource(*parsedConfig.Terraform.Source, path)
+		if sourceErr != nil {
+			file, line := v.locate(path, includes, "source", blockRef{Type: "terraform"})
+			v.add(CheckParse, SeverityError, file, line, "source %s does not parse: %s", *parsedConfig.Terraform.Source, sourceErr)
+		}
+	}
+	if terraformDir := g.terraformDir(path, source); terraformDir != "" && sourceErr == nil {
+		moduleCalls, err := g.moduleCallTree(terraformDir)
+		if err != nil {
+			v.add(CheckParse, SeverityError, terraformDir, 0, "terraform code does not parse: %s", err)
+		}
+		for _, call := range moduleCallCycles(moduleCalls) {
+			v.add(CheckModuleCycle, SeverityError, call.pos.Filename, call.pos.Line, "module %q calls %s, which is already calling it", call.Name, call.Dir)
+		}
+	}
+
+	// Terraform fails on missing required var files. Optional ones are allowed to be missing
+	if parsedConfig.Terraform == nil {
+		return
+	}
+	for _, arg := range parsedConfig.Terraform.ExtraArgs {
+		extraArguments := blockRef{Type: "extra_arguments", Labels: []string{arg.Name}}
+		if arg.RequiredVarFiles != nil {
+			for _, varFile := range *arg.RequiredVarFiles {
+				if !v.pathExists(path, varFile) {
+					file, line := v.locate(path, includes, "required_var_files", blockRef{Type: "terraform"}, extraArguments)
+					v.add(CheckVarFile, SeverityError, file, line, "required var file %s does not exist", g.displayPath(varFile))
+				}
+			}
+		}
+		if arg.Arguments != nil {
+			for _, cliFlag := range *arg.Arguments {
+				if !strings.HasPrefix(cliFlag, "-var-file=") {
+					continue
+				}
+				varFile := strings.TrimPrefix(cliFlag, "-var-file=")
+				if !v.pathExists(path, varFile) {
+					file, line := v.locate(path, includes, "arguments", blockRef{Type: "terraform"}, extraArguments)
+					v.add(CheckVarFile, SeverityError, file, line, "var file %s does not exist", g.displayPath(filepath.Clean(varFile)))
+				}
+			}
+		}
+	}
+}
+
+// pathExists tells whether `path`, relative to the config at `configPath` unless absolute, matches any file. Globs are
+// supported
+func (v *validator) pathExists(configPath string, path string) bool {
+	absolutePath := path
+	if !filepath.IsAbs(absolutePath) {
+		absolutePath = v.g.makePathAbsolute(path, configPath)
+	}
+	matched, err := globMatches(absolutePath)
+	return err == nil && matched
+}
+
+// findCycles reports every cycle in the recorded edges once, from the config that sorts first within it
+func (v *validator) findCycles() {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	stack := []string{}
+	reported := map[string]bool{}
+
+	var visit func(path string)
+	visit = func(path string) {
+		state[path] = visiting
+		stack = append(stack, path)
+
+		dependencyPaths := append([]string{}, v.g.edges[path]...)
+		sort.Strings(dependencyPaths)
+		for _, dependencyPath := range dependencyPaths {
+			switch state[dependencyPath] {
+			case unvisited:
+				visit(dependencyPath)
+			case visiting:
+				cycle := append([]string{}, stack[slices.Index(stack, dependencyPath):]...)
+
+				// Rotate the cycle to start from its smallest config, so each cycle is reported once
+				start := slices.Index(cycle, slices.Min(cycle))
+				cycle = append(cycle[start:], cycle[:start]...)
+				key := strings.Join(cycle, "\x00")
+				if reported[key] {
+					continue
+				}
+				reported[key] = true
+
+				dirs := []string{}
+				for _, configPath := range append(cycle, cycle[0]) {
+					dirs = append(dirs, v.g.relativeModuleDir(configPath))
+				}
+				next := cycle[0]
+				if len(cycle) > 1 {
+					next = cycle[1]
+				}
+				v.add(CheckCycle, SeverityError, cycle[0], v.edgeLines[cycle[0]][next], "dependency cycle: %s", strings.Join(dirs, " -> "))
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[path] = visited
+	}
+
+	paths := []string{}
+	for path := range v.g.edges {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if state[path] == unvisited {
+			visit(path)
+		}
+	}
+}
+
+// Validate checks every config under the root, without stopping at the first problem:
+//   - configs and the configs they include parse
+//   - `dependency` and `dependencies` paths point at existing modules
+//   - extra dependencies and required var files exist
+//   - there are no dependency cycles
+//   - no two configs resolve to the same module directory
+//   - no two modules store their state at the same location
+//
+// The returned error is only set when the checks could not run.
+func (g *Generator) Validate(ctx context.Context) ([]Finding, error) {
+	g.reset()
+	v := &validator{g: g, findings: []Finding{}, edgeLines: map[string]map[string]int{}}
+
+	log.Info("Working directory: ", g.root)
+	terragruntFiles, err := g.getAllTerragruntFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// Terragrunt silently ignores `terragrunt.hcl.json` when `terragrunt.hcl` sits next to it
+	configsBySourcePath := map[string][]string{}
+	for _, terragruntPath := range terragruntFiles {
+		sourcePath := g.relativeModuleDir(terragruntPath)
+		for _, name := range []string{config.DefaultTerragruntConfigPath, config.DefaultTerragruntJsonConfigPath} {
+			configPath := filepath.Join(filepath.Dir(terragruntPath), name)
+			if util.FileExists(configPath) && !slices.Contains(configsBySourcePath[sourcePath], configPath) {
+				configsBySourcePath[sourcePath] = append(configsBySourcePath[sourcePath], configPath)
+			}
+		}
+	}
+	for sourcePath, configPaths := range configsBySourcePath {
+		sort.Strings(configPaths)
+		for _, configPath := range configPaths[1:] {
+			v.add(CheckDuplicateSourcePath, SeverityError, configPath, 0, "module %s is also defined by %s", sourcePath, g.relativePath(configPaths[0]))
+		}
+	}
+
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	sem := semaphore.NewWeighted(g.opts.Parallelism)
+	for _, terragruntPath := range terragruntFiles {
+		terragruntPath := terragruntPath // https://golang.org/doc/faq#closures_and_goroutines
+
+		if err := sem.Acquire(groupCtx, 1); err != nil {
+			break
+		}
+		errGroup.Go(func() error {
+			defer sem.Release(1)
+
+			log.Debug("Validating ", terragruntPath)
+			v.validateModule(terragruntPath)
+			return nil
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	v.findCycles()
+
+	for location, configPaths := range g.duplicateStateLocations() {
+		for _, configPath := range configPaths[1:] {
+			v.add(CheckDuplicateStateKey, SeverityError, configPath, 0, "module %s stores its state at the same location as %s: %s", g.relativeModuleDir(configPath), g.relativeModuleDir(configPaths[0]), location)
+		}
+	}
+
+	// Cycles in Terraform code shared by several modules are only reported once
+	sortFindings(v.findings)
+	return slices.Compact(v.findings), nil
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Workspace is a Terraform workspace a module is applied in, from the `gitlab_ci_workspaces` local
+type Workspace struct {
+	Name string
+	// Extra environment variables of the jobs of the workspace. Empty when the local is a list
+	Env map[string]string
+}
+
+// parseWorkspaces reads the `gitlab_ci_workspaces` local, either a list of names or an object of names to their extra
+// environment variables. Names are deduplicated, and sorted for an object
+func parseWorkspaces(value cty.Value) []Workspace {
+	workspaces := []Workspace{}
+	if !value.IsKnown() || value.IsNull() {
+		return workspaces
+	}
+
+	if !value.Type().IsObjectType() && !value.Type().IsMapType() {
+		seen := map[string]bool{}
+		for _, name := range stringList(value) {
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			workspaces = append(workspaces, Workspace{Name: name, Env: map[string]string{}})
+		}
+		return workspaces
+	}
+
+	for name, envValue := range value.AsValueMap() {
+		workspace := Workspace{Name: name, Env: map[string]string{}}
+		if envValue.IsKnown() && !envValue.IsNull() && (envValue.Type().IsObjectType() || envValue.Type().IsMapType()) {
+			for key, variable := range envValue.AsValueMap() {
+				variable, err := convert.Convert(variable, cty.String)
+				if err != nil || !variable.IsKnown() || variable.IsNull() {
+					continue
+				}
+				workspace.Env[key] = variable.AsString()
+			}
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].Name < workspaces[j].Name
+	})
+	return workspaces
+}
+
+// fanOut returns the entries to generate jobs for from the module: one per workspace, or the module itself when it has
+// none. Entries share everything but their workspace and ID
+func (d DependencyDirs) fanOut() []DependencyDirs {
+	if len(d.workspaces) == 0 {
+		return []DependencyDirs{d}
+	}
+
+	entries := []DependencyDirs{}
+	for _, workspace := range d.workspaces {
+		entry := d
+		entry.ID = d.ID + "@" + workspace.Name
+		entry.Workspace = workspace.Name
+		entry.WorkspaceEnv = workspace.Env
+		entries = append(entries, entry)
+	}
+	return entries
+}
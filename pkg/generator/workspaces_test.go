@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestParseWorkspaces(t *testing.T) {
+	tests := []struct {
+		name  string
+		value cty.Value
+		want  []Workspace
+	}{
+		{name: "null", value: cty.NullVal(cty.List(cty.String)), want: []Workspace{}},
+		{name: "unknown", value: cty.UnknownVal(cty.List(cty.String)), want: []Workspace{}},
+		{
+			name:  "list keeps its order without duplicates",
+			value: cty.TupleVal([]cty.Value{cty.StringVal("globex"), cty.StringVal("acme"), cty.StringVal("globex"), cty.StringVal("")}),
+			want:  []Workspace{{Name: "globex", Env: map[string]string{}}, {Name: "acme", Env: map[string]string{}}},
+		},
+		{
+			name: "object is sorted, with its variables converted to strings",
+			value: cty.ObjectVal(map[string]cty.Value{
+				"us": cty.ObjectVal(map[string]cty.Value{"AWS_REGION": cty.StringVal("us-east-1")}),
+				"eu": cty.ObjectVal(map[string]cty.Value{
+					"AWS_REGION": cty.StringVal("eu-west-1"),
+					"REPLICAS":   cty.NumberIntVal(2),
+					"DISABLED":   cty.NullVal(cty.String),
+					"ZONES":      cty.TupleVal([]cty.Value{cty.StringVal("a")}),
+				}),
+			}),
+			want: []Workspace{
+				{Name: "eu", Env: map[string]string{"AWS_REGION": "eu-west-1", "REPLICAS": "2"}},
+				{Name: "us", Env: map[string]string{"AWS_REGION": "us-east-1"}},
+			},
+		},
+		{
+			name:  "object without variables",
+			value: cty.ObjectVal(map[string]cty.Value{"acme": cty.NullVal(cty.DynamicPseudoType)}),
+			want:  []Workspace{{Name: "acme", Env: map[string]string{}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseWorkspaces(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	project := DependencyDirs{SourcePath: "network", ID: "network", Dependencies: []string{"network/**/*"}}
+	if got := project.fanOut(); !reflect.DeepEqual(got, []DependencyDirs{project}) {
+		t.Errorf("without workspaces: got %+v, want the module itself", got)
+	}
+
+	// The entry of a matrix set keeps its ID as a prefix, and its set
+	project.ID = "network[REGION=eu-west-1]"
+	project.Matrix = map[string]string{"REGION": "eu-west-1"}
+	project.workspaces = []Workspace{
+		{Name: "acme", Env: map[string]string{}},
+		{Name: "globex", Env: map[string]string{"TIER": "gold"}},
+	}
+	entries := project.fanOut()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for i, want := range []struct{ id, workspace string }{
+		{id: "network[REGION=eu-west-1]@acme", workspace: "acme"},
+		{id: "network[REGION=eu-west-1]@globex", workspace: "globex"},
+	} {
+		entry := entries[i]
+		if entry.ID != want.id || entry.Workspace != want.workspace || !reflect.DeepEqual(entry.WorkspaceEnv, project.workspaces[i].Env) {
+			t.Errorf("entry %d: got ID %s, workspace %s and env %v", i, entry.ID, entry.Workspace, entry.WorkspaceEnv)
+		}
+		if entry.SourcePath != project.SourcePath || !reflect.DeepEqual(entry.Matrix, project.Matrix) || !reflect.DeepEqual(entry.Dependencies, project.Dependencies) {
+			t.Errorf("entry %d: got %+v, want everything but its workspace shared with the module", i, entry)
+		}
+	}
+}
+
+func TestCollectWorkspaces(t *testing.T) {
+	g := newTestGenerator(t, "projects/workspaces", nil)
+	model, err := g.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		workspace string
+		env       map[string]string
+	}
+	got := map[string]entry{}
+	ids := []string{}
+	for _, project := range model.Modules {
+		got[project.ID] = entry{workspace: project.Workspace, env: project.WorkspaceEnv}
+		ids = append(ids, project.ID)
+	}
+
+	// Entries are sorted by source path, and keep the order of their workspaces
+	wantIDs := []string{"regional@eu", "regional@us", "shared", "tenants@acme", "tenants@globex"}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Fatalf("got entries %v, want %v", ids, wantIDs)
+	}
+	want := map[string]entry{
+		"regional@eu":    {workspace: "eu", env: map[string]string{"AWS_REGION": "eu-west-1", "REPLICAS": "2"}},
+		"regional@us":    {workspace: "us", env: map[string]string{"AWS_REGION": "us-east-1"}},
+		"shared":         {},
+		"tenants@acme":   {workspace: "acme", env: map[string]string{}},
+		"tenants@globex": {workspace: "globex", env: map[string]string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Edges stay between modules, whatever their workspaces
+	if edges := model.Graph.Edges["tenants"]; !reflect.DeepEqual(edges, []string{"shared"}) {
+		t.Errorf("edges of tenants: got %v, want [shared]", edges)
+	}
+}
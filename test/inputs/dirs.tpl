@@ -0,0 +1,20 @@
+needs={{ .Needs }} workload={{ .Workload }}
+{{- range .Dirs }}
+{{ .ID }}
+  changes: {{ join "," (sortAlpha .Dependencies) }}
+{{- with .Environment }}
+  environment: {{ . }}
+{{- end }}
+{{- with .StateKey }}
+  state_key: {{ . }}
+{{- end }}
+{{- with .Source }}
+  source: {{ .Type }} {{ .URL }}
+{{- end }}
+{{- with .Meta }}
+  meta: {{ . }}
+{{- end }}
+{{- end }}
+{{- range $path, $dependencies := .Graph.Edges }}
+edge {{ $path }} -> {{ join "," $dependencies }}
+{{- end }}
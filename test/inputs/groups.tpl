@@ -0,0 +1,8 @@
+workload={{ .Workload }}
+{{- range .Dirs }}
+{{ .ID }}
+  meta: {{ .Meta }}
+{{- range .DependenciesGrouped }}
+  group {{ .Environment }}: {{ join "," (sortAlpha .Items) }}
+{{- end }}
+{{- end }}
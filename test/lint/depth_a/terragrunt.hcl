@@ -0,0 +1,11 @@
+locals {
+  gitlab_ci_environment = "dev"
+}
+
+terraform {
+  source = "../modules/wrapper"
+}
+
+dependency "b" {
+  config_path = "../depth_b"
+}
@@ -0,0 +1,7 @@
+locals {
+  gitlab_ci_environment = "prod"
+}
+
+terraform {
+  source = "git::https://github.com/example/modules.git//vpc?ref=main"
+}
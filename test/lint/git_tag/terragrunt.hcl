@@ -0,0 +1,7 @@
+locals {
+  gitlab_ci_environment = "sandbox"
+}
+
+terraform {
+  source = "git::git@github.com:example/modules.git//vpc?ref=v1.2.0"
+}
@@ -0,0 +1,7 @@
+locals {
+  gitlab_ci_environment = "prod"
+}
+
+terraform {
+  source = "github.com/example/modules//vpc"
+}
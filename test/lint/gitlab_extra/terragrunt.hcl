@@ -0,0 +1,11 @@
+locals {
+  gitlab_ci_environment = "dev"
+  gitlab_ci_extra_dependencies = [
+    "/etc/hosts",
+    "${get_terragrunt_dir()}/inputs.yaml",
+  ]
+}
+
+terraform {
+  source = "../modules/wrapper"
+}
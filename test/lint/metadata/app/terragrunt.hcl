@@ -0,0 +1,7 @@
+locals {
+  gitlab_ci_environment = "dev"
+}
+
+terraform {
+  source = "../../modules/wrapper"
+}
@@ -0,0 +1,4 @@
+locals {
+  # Takes precedence over the gitlab_ci_environment local of the modules
+  environment = "staging"
+}
@@ -0,0 +1,8 @@
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+
+module "pinned" {
+  source  = "terraform-aws-modules/s3-bucket/aws"
+  version = "~> 3.0"
+}
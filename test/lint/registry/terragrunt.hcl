@@ -0,0 +1,7 @@
+locals {
+  gitlab_ci_environment = "dev"
+}
+
+terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws"
+}
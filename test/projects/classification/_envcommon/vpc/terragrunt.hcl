@@ -0,0 +1,8 @@
+# Shared by the modules including it, not a module itself
+locals {
+  gitlab_ci_role = "ignore"
+}
+
+terraform {
+  source = "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git?ref=v5.0.0"
+}
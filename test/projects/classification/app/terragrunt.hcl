@@ -0,0 +1,7 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+include "envcommon" {
+  path = find_in_parent_folders("_envcommon/vpc/terragrunt.hcl")
+}
@@ -0,0 +1,3 @@
+include "shared" {
+  path = "${get_terragrunt_dir()}/../shared/terragrunt.hcl"
+}
@@ -0,0 +1,3 @@
+locals {
+  gitlab_ci_role = "module"
+}
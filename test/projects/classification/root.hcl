@@ -0,0 +1,6 @@
+# Root config included by the modules, found under the root like terragrunt.hcl but always a parent
+terraform {
+  extra_arguments "common_vars" {
+    commands = ["plan", "apply"]
+  }
+}
@@ -0,0 +1,4 @@
+# Has a terraform source, but is a parent as consumer includes it
+terraform {
+  source = "git::https://github.com/terraform-aws-modules/terraform-aws-s3-bucket.git?ref=v3.15.0"
+}
@@ -0,0 +1,4 @@
+# Included by no module, still a parent as it is named like a root config
+terraform {
+  source = "git::https://github.com/terraform-aws-modules/terraform-aws-vpc.git?ref=v5.0.0"
+}
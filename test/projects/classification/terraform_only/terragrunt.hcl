@@ -0,0 +1,4 @@
+# No include nor source, but Terragrunt runs the .tf files next to it
+inputs = {
+  name = "terraform_only"
+}
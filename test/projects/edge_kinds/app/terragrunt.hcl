@@ -0,0 +1,21 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//app?ref=v1.0.0"
+}
+
+# Plans need the database to be applied
+dependency "db" {
+  config_path = "../db"
+}
+
+dependency "vpc" {
+  config_path  = "../vpc"
+  skip_outputs = true
+}
+
+dependencies {
+  paths = ["../vpc", "../monitoring"]
+}
+
+inputs = {
+  endpoint = dependency.db.outputs.endpoint
+}
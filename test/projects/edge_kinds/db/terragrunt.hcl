@@ -0,0 +1,17 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//db?ref=v1.0.0"
+}
+
+# Plans can run before the VPC is applied
+dependency "vpc" {
+  config_path = "../vpc"
+
+  mock_outputs = {
+    vpc_id = "vpc-mock"
+  }
+  mock_outputs_allowed_terraform_commands = ["validate", "plan"]
+}
+
+inputs = {
+  vpc_id = dependency.vpc.outputs.vpc_id
+}
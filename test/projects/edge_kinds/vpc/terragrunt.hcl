@@ -0,0 +1,3 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//vpc?ref=v1.0.0"
+}
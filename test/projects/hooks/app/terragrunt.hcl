@@ -0,0 +1,21 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+dependency "db" {
+  config_path = "../db"
+}
+
+terraform {
+  after_hook "notify" {
+    commands    = ["apply"]
+    execute     = ["./scripts/notify.sh", "app"]
+    working_dir = ".."
+  }
+}
+
+generate "backend" {
+  path      = "backend.tf"
+  if_exists = "overwrite"
+  contents  = file("../templates/backend.tf")
+}
@@ -0,0 +1,11 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+terraform {
+  # No argument is a file of the repo
+  before_hook "fmt" {
+    commands = ["plan"]
+    execute  = ["terraform", "fmt", "-check"]
+  }
+}
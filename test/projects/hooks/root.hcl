@@ -0,0 +1,13 @@
+# Every module gets the same provider, rendered from a template
+generate "provider" {
+  path      = "provider.tf"
+  if_exists = "overwrite"
+  contents  = templatefile("templates/provider.tf.tpl", { region = "eu-west-1" })
+}
+
+terraform {
+  before_hook "validate" {
+    commands = ["plan", "apply"]
+    execute  = ["bash", "${get_parent_terragrunt_dir()}/scripts/validate.sh"]
+  }
+}
@@ -0,0 +1,17 @@
+terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"
+}
+
+locals {
+  # Directories and globs, which must match at least one file
+  gitlab_ci_extra_dependencies = [
+    "../shared",
+    "../shared_vars/*.yaml",
+  ]
+
+  # Documentation changes do not need a plan
+  gitlab_ci_ignore_changes = [
+    "README.md",
+    "docs/",
+  ]
+}
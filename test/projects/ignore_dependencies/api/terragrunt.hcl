@@ -0,0 +1,14 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//api?ref=v1.0.0"
+}
+
+# Changes to the VPC trigger the plans, but not the ones of the zone it depends on
+locals {
+  gitlab_ci_ignore_dependencies = {
+    cascade = ["../vpc"]
+  }
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
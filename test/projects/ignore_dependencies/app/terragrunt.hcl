@@ -0,0 +1,16 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//app?ref=v1.0.0"
+}
+
+# The shared DNS zone changes often, and plans don't need to run again when it does
+locals {
+  gitlab_ci_ignore_dependencies = ["../dns"]
+}
+
+dependency "dns" {
+  config_path = "../dns"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
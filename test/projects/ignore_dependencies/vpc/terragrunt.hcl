@@ -0,0 +1,7 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//vpc?ref=v1.0.0"
+}
+
+dependency "dns" {
+  config_path = "../dns"
+}
@@ -0,0 +1,14 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//worker?ref=v1.0.0"
+}
+
+# Only the modules the VPC depends on trigger the plans
+locals {
+  gitlab_ci_ignore_dependencies = {
+    changes = ["../v*"]
+  }
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
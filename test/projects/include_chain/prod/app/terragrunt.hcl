@@ -0,0 +1,15 @@
+include "env" {
+  path = find_in_parent_folders("env.hcl")
+}
+
+terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"
+}
+
+locals {
+  # Documentation of this module lives under docs, and its README.md does trigger
+  gitlab_ci_merge = {
+    gitlab_ci_ignore_changes = "replace"
+  }
+  gitlab_ci_ignore_changes = ["docs/"]
+}
@@ -0,0 +1,12 @@
+include "env" {
+  path = find_in_parent_folders("env.hcl")
+}
+
+terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"
+}
+
+locals {
+  # Appended to the *.md of root.hcl
+  gitlab_ci_ignore_changes = ["*.tfvars"]
+}
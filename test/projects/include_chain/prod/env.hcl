@@ -0,0 +1,8 @@
+# Second level, included by every module of the environment
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+locals {
+  gitlab_ci_environment = "production"
+}
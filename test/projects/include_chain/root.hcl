@@ -0,0 +1,6 @@
+# First level, included by every env.hcl
+locals {
+  gitlab_ci_environment       = "development"
+  gitlab_ci_ignore_changes    = ["*.md"]
+  extra_atlantis_dependencies = [find_in_parent_folders("common.yaml")]
+}
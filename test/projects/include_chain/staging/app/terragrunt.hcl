@@ -0,0 +1,7 @@
+include "env" {
+  path = find_in_parent_folders("env.hcl")
+}
+
+terraform {
+  source = "tfr:///terraform-aws-modules/vpc/aws?version=5.0.0"
+}
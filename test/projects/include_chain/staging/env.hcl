@@ -0,0 +1,7 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+locals {
+  gitlab_cicd_skip = true
+}
@@ -0,0 +1,8 @@
+# The Terraform code reads the outputs of network through its state, without any dependency block
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+terraform {
+  source = "../modules/app"
+}
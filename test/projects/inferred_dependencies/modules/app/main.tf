@@ -0,0 +1,42 @@
+terraform {
+  backend "s3" {}
+}
+
+variable "environment" {
+  type    = string
+  default = "prod"
+}
+
+data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "acme-terraform-state"
+    key    = "network/terraform.tfstate"
+    region = "eu-west-1"
+  }
+}
+
+# Read from the acme workspace of tenants, whose key is written with a doubled slash
+data "terraform_remote_state" "tenant" {
+  backend   = "s3"
+  workspace = "acme"
+  config = {
+    bucket = "acme-terraform-state"
+    key    = "tenants//terraform.tfstate"
+    region = "eu-west-1"
+  }
+}
+
+# Depends on a variable, so it can't be located
+data "terraform_remote_state" "dns" {
+  backend = "s3"
+  config = {
+    bucket = "acme-terraform-state"
+    key    = "${var.environment}/dns/terraform.tfstate"
+    region = "eu-west-1"
+  }
+}
+
+output "subnet" {
+  value = data.terraform_remote_state.network.outputs.vpc_id
+}
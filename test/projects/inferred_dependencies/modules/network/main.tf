@@ -0,0 +1,7 @@
+terraform {
+  backend "s3" {}
+}
+
+output "vpc_id" {
+  value = "vpc-0123456789"
+}
@@ -0,0 +1,7 @@
+terraform {
+  backend "s3" {}
+}
+
+output "tenant" {
+  value = terraform.workspace
+}
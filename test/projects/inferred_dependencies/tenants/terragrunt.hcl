@@ -0,0 +1,11 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+terraform {
+  source = "../modules/tenant"
+}
+
+locals {
+  gitlab_ci_workspaces = ["acme", "globex"]
+}
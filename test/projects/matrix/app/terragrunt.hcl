@@ -0,0 +1,8 @@
+# Depends on every entry of network
+terraform {
+  source = "git::git@github.com:example/modules.git//app?ref=v1.0.0"
+}
+
+dependencies {
+  paths = ["../network"]
+}
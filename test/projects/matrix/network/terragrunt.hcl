@@ -0,0 +1,24 @@
+# Deployed once per region, each with its own DNS zone and variables
+locals {
+  gitlab_ci_matrix = [
+    { REGION = "us-east-1" },
+    { REGION = "eu-west-1" },
+    { REGION = "ap-south-1" },
+  ]
+
+  region           = get_env("REGION")
+  gitlab_cicd_skip = local.region == "ap-south-1"
+}
+
+terraform {
+  source = "git::git@github.com:example/modules.git//network?ref=v1.0.0"
+
+  extra_arguments "region" {
+    commands           = ["plan", "apply"]
+    required_var_files = ["${get_terragrunt_dir()}/${local.region}.tfvars"]
+  }
+}
+
+dependency "dns" {
+  config_path = "../dns-${local.region}"
+}
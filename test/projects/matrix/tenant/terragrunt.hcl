@@ -0,0 +1,14 @@
+# The tenants are listed in the config file of test/projects/matrix. Without it, the module is evaluated once for the
+# shared tenant
+locals {
+  tenant = get_env("TENANT", "shared")
+}
+
+terraform {
+  source = "git::git@github.com:example/modules.git//tenant?ref=v1.0.0"
+
+  extra_arguments "tenant" {
+    commands           = ["plan", "apply"]
+    optional_var_files = ["${get_terragrunt_dir()}/${local.tenant}.tfvars"]
+  }
+}
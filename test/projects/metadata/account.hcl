@@ -0,0 +1,4 @@
+locals {
+  account_name   = "main"
+  aws_account_id = "111111111111"
+}
@@ -0,0 +1,7 @@
+# Directories are not named after the environment, which only the metadata knows
+locals {
+  environment = "prod"
+  tags = {
+    tier = "production"
+  }
+}
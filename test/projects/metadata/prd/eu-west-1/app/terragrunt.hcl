@@ -0,0 +1,11 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//app?ref=v1.0.0"
+}
+
+dependency "dns" {
+  config_path = "../../../shared/dns"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
@@ -0,0 +1,4 @@
+# No env.hcl above it, so it has no environment and is selected on its path
+terraform {
+  source = "git::git@github.com:example/modules.git//dns?ref=v1.0.0"
+}
@@ -0,0 +1,6 @@
+locals {
+  environment = "staging"
+  tags = {
+    tier = "staging"
+  }
+}
@@ -0,0 +1,13 @@
+# Copied from `app`, but with a hardcoded key that collides with it
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "terraform-state"
+    key    = "app/terraform.tfstate"
+    region = "eu-west-1"
+  }
+}
+
+terraform {
+  source = "git::git@github.com:transcend-io/terraform-aws-fargate-container?ref=v0.0.4"
+}
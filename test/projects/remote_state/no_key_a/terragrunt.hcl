@@ -0,0 +1,11 @@
+# The gcs backend is given no prefix, so the state has no key and is not checked for duplicates
+remote_state {
+  backend = "gcs"
+  config = {
+    bucket = "terraform-state"
+  }
+}
+
+terraform {
+  source = "git::git@github.com:transcend-io/terraform-aws-fargate-container?ref=v0.0.4"
+}
@@ -0,0 +1,17 @@
+include "root" {
+  path   = find_in_parent_folders()
+  expose = true
+}
+
+terraform {
+  source = "git::git@github.com:transcend-io/terraform-aws-fargate-container?ref=${include.root.locals.module_version}"
+}
+
+locals {
+  env = read_terragrunt_config(find_in_parent_folders("env.hcl")).locals.environment
+
+  extra_atlantis_dependencies = [
+    "${local.env}.tfvars",
+    "${run_cmd("echo", "generated")}.json",
+  ]
+}
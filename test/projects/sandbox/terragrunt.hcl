@@ -0,0 +1,3 @@
+locals {
+  module_version = run_cmd("--terragrunt-quiet", "echo", "v1.2.3")
+}
@@ -0,0 +1,6 @@
+terraform {
+  # Lives in this repo, under `source_map/modules`. Map it with `--root test/projects --source-map
+  # git::ssh://git@github.com/example/infra.git=source_map`, or with `--source-map git::ssh://git@github.com/example/infra.git=.`
+  # when the root is `test/projects/source_map` itself
+  source = "git::ssh://git@github.com/example/infra.git//modules/vpc?ref=main"
+}
@@ -0,0 +1,9 @@
+variable "cidr_block" {
+  type        = string
+  description = "CIDR block of the VPC"
+}
+
+variable "name" {
+  type    = string
+  default = "main"
+}
@@ -0,0 +1,10 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//regional?ref=v1.0.0"
+}
+
+locals {
+  gitlab_ci_workspaces = {
+    us = { AWS_REGION = "us-east-1" }
+    eu = { AWS_REGION = "eu-west-1", REPLICAS = 2 }
+  }
+}
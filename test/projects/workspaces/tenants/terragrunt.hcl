@@ -0,0 +1,12 @@
+terraform {
+  source = "git::git@github.com:example/modules.git//tenant?ref=v1.0.0"
+}
+
+# Applied once per tenant, from the same directory
+locals {
+  gitlab_ci_workspaces = ["acme", "globex", "acme"]
+}
+
+dependency "shared" {
+  config_path = "../shared"
+}
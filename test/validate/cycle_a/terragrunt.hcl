@@ -0,0 +1,7 @@
+terraform {
+  source = "../modules/null"
+}
+
+dependency "b" {
+  config_path = "../cycle_b"
+}
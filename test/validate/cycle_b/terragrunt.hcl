@@ -0,0 +1,7 @@
+terraform {
+  source = "../modules/null"
+}
+
+dependencies {
+  paths = ["../cycle_a"]
+}
@@ -0,0 +1,15 @@
+locals {
+  extra_atlantis_dependencies = [
+    "missing.tfvars",
+    "*.yaml",
+  ]
+}
+
+terraform {
+  source = "../modules/null"
+
+  extra_arguments "vars" {
+    commands           = ["plan"]
+    required_var_files = ["${get_terragrunt_dir()}/missing.tfvars"]
+  }
+}
@@ -0,0 +1,7 @@
+terraform {
+  source = "../modules/null"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
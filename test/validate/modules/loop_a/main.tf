@@ -0,0 +1,3 @@
+module "loop_b" {
+  source = "${path.module}/../loop_b"
+}
@@ -0,0 +1,3 @@
+module "loop_a" {
+  source = "../loop_a"
+}
@@ -0,0 +1 @@
+# Intentionally empty module
@@ -0,0 +1,7 @@
+include "parent" {
+  path = "../parent.hcl"
+}
+
+terraform {
+  source = "git::https://github.com/example/modules.git//vpc?ref=v1.0.0"
+}
@@ -0,0 +1,7 @@
+# Evaluates, but its settings can't be merged into the ones of the modules including it
+locals {
+  gitlab_ci_merge = {
+    gitlab_ci_ignore_changes = "prepend"
+  }
+  gitlab_ci_ignore_changes = ["docs/"]
+}